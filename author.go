@@ -0,0 +1,61 @@
+package rss
+
+import (
+	"strings"
+
+	"github.com/haleyrc/rss/parser"
+)
+
+// Author is someone credited with writing a feed or item. Authors are shared
+// between feeds and are identified by their name and email address, either of
+// which may be empty but not both.
+type Author struct {
+	ID     int64  `db:"id" json:"id"`
+	Name   string `db:"name" json:"name"`
+	Email  string `db:"email" json:"email,omitempty"`
+	URL    string `db:"url" json:"url,omitempty"`
+	Avatar string `db:"avatar" json:"avatar,omitempty"`
+}
+
+// newAuthors converts parsed authors, resolving their links against base.
+// Feeds often credit the same person twice, for example in both <author> and
+// dc:creator, so people with the same name are merged.
+func newAuthors(in []parser.Person, base string) []*Author {
+	var authors []*Author
+	byKey := make(map[string]*Author)
+	for _, p := range in {
+		a := &Author{
+			Name:   strings.TrimSpace(p.Name),
+			Email:  strings.TrimSpace(p.Email),
+			URL:    parser.ResolveURL(base, p.URL),
+			Avatar: parser.ResolveURL(base, p.Avatar),
+		}
+		key := strings.ToLower(a.Name)
+		if key == "" {
+			key = strings.ToLower(a.Email)
+		}
+		if key == "" {
+			continue
+		}
+		if existing, ok := byKey[key]; ok {
+			existing.merge(a)
+			continue
+		}
+		byKey[key] = a
+		authors = append(authors, a)
+	}
+	return authors
+}
+
+// merge fills in any details missing from a with those of other.
+func (a *Author) merge(other *Author) {
+	if a.Email == "" {
+		a.Email = other.Email
+	}
+	if a.URL == "" {
+		a.URL = other.URL
+	}
+	if a.Avatar == "" {
+		a.Avatar = other.Avatar
+	}
+}
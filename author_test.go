@@ -0,0 +1,48 @@
+package rss_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromChannelAuthors(t *testing.T) {
+	input := `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "T",
+		"home_page_url": "http://example.com/",
+		"authors": [{"name": "The Editor", "avatar": "/editor.png"}],
+		"items": [{
+			"id": "1",
+			"title": "Post",
+			"url": "http://example.com/1",
+			"date_published": "2019-04-01T10:00:00Z",
+			"authors": [{"name": "Jo"}, {"name": "jo", "url": "/jo"}, {"name": " "}]
+		}]
+	}`
+
+	jsonFeed, err := parser.Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed, err := rss.NewFromChannel(jsonFeed.Channel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := rss.Author{Name: "The Editor", Avatar: "http://example.com/editor.png"}
+	if len(feed.Authors) != 1 || *feed.Authors[0] != want {
+		t.Errorf("expected feed author %+v, got %+v", want, feed.Authors)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(feed.Items))
+	}
+
+	want = rss.Author{Name: "Jo", URL: "http://example.com/jo"}
+	got := feed.Items[0].Authors
+	if len(got) != 1 || *got[0] != want {
+		t.Errorf("expected item author %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,38 @@
+package rss
+
+import (
+	"strings"
+
+	"github.com/haleyrc/rss/parser"
+)
+
+// Category is a topic that a feed or item is filed under. Domain identifies
+// the taxonomy the name belongs to, if the feed says, so that the same name
+// from two different schemes is kept apart. Categories are shared between
+// feeds, and names are matched without regard to case.
+type Category struct {
+	ID     int64  `db:"id" json:"id"`
+	Name   string `db:"name" json:"name"`
+	Domain string `db:"domain" json:"domain,omitempty"`
+}
+
+// newCategories converts parsed categories, dropping those without a name and
+// any that repeat an earlier one.
+func newCategories(in []parser.Category) []*Category {
+	var categories []*Category
+	seen := make(map[Category]bool)
+	for _, c := range in {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			continue
+		}
+		domain := strings.TrimSpace(c.Domain)
+		key := Category{Name: strings.ToLower(name), Domain: domain}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		categories = append(categories, &Category{Name: name, Domain: domain})
+	}
+	return categories
+}
@@ -0,0 +1,52 @@
+package rss_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromChannelCategories(t *testing.T) {
+	input := `<rss version="2.0"><channel>
+		<title>T</title><description>D</description><link>http://example.com/</link>
+		<category>Technology</category>
+		<item>
+			<title>Post</title>
+			<link>http://example.com/1</link>
+			<pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate>
+			<category>Go</category>
+			<category>go</category>
+			<category domain="http://example.com/tags">go</category>
+			<category>  </category>
+		</item>
+	</channel></rss>`
+
+	xmlFeed, err := parser.Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed, err := rss.NewFromChannel(xmlFeed.Channel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(feed.Categories) != 1 || feed.Categories[0].Name != "Technology" {
+		t.Errorf("expected feed category %q, got %v", "Technology", feed.Categories)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(feed.Items))
+	}
+
+	want := []rss.Category{{Name: "Go"}, {Name: "go", Domain: "http://example.com/tags"}}
+	got := feed.Items[0].Categories
+	if len(got) != len(want) {
+		t.Fatalf("expected %d categories, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Errorf("expected category %v, got %v", want[i], *got[i])
+		}
+	}
+}
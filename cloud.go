@@ -0,0 +1,16 @@
+package rss
+
+import (
+	"time"
+)
+
+// CloudRegistration records that we asked the rssCloud service at Endpoint to
+// notify us when the feed published at Topic changes. Clouds forget
+// registrations after 25 hours, so RegisteredAt is used to renew them in
+// time. It is nil until the cloud has accepted the registration.
+type CloudRegistration struct {
+	FeedID       int64      `db:"feed_id" json:"feedId"`
+	Endpoint     string     `db:"endpoint" json:"endpoint"`
+	Topic        string     `db:"topic" json:"topic"`
+	RegisteredAt *time.Time `db:"registered_at" json:"registeredAt,omitempty"`
+}
@@ -1,8 +1,21 @@
 module github.com/haleyrc/rss
 
+go 1.27.1
+
 require (
+	github.com/andybalholm/brotli v1.0.0
 	github.com/gorilla/mux v1.7.1
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.0.0
 	github.com/pkg/errors v0.8.1
+	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3
+	golang.org/x/text v0.3.2
+)
+
+require (
+	github.com/go-sql-driver/mysql v1.4.0 // indirect
+	github.com/mattn/go-sqlite3 v1.9.0 // indirect
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 // indirect
+	golang.org/x/sys v0.0.0-20190215142949-d0b11bdaac8a // indirect
+	golang.org/x/tools v0.0.0-20180917221912-90fa682c2a6e // indirect
 )
@@ -0,0 +1,280 @@
+// Package markup is a small, forgiving HTML tokenizer.
+//
+// It exists because feed content is publisher supplied HTML of wildly varying
+// quality and we need to pick it apart in a few places (sanitising item
+// content, rewriting URLs, finding feed links on web pages) without pulling in
+// a full HTML5 parser. It never fails: anything it can not make sense of is
+// returned as text.
+package markup
+
+import (
+	"html"
+	"strings"
+)
+
+type TokenType int
+
+const (
+	TextToken TokenType = iota
+	StartTagToken
+	EndTagToken
+	SelfClosingTagToken
+	CommentToken
+	DoctypeToken
+)
+
+type Attribute struct {
+	Key string
+	Val string
+}
+
+// Token is a single piece of an HTML document. For tags, Data holds the
+// lowercased tag name; for text, it holds the unescaped text.
+type Token struct {
+	Type TokenType
+	Data string
+	Attr []Attribute
+}
+
+// Get returns the value of the named attribute and whether it was present.
+func (t Token) Get(key string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// String renders the token back to HTML, escaping text and attribute values.
+func (t Token) String() string {
+	switch t.Type {
+	case TextToken:
+		return html.EscapeString(t.Data)
+	case StartTagToken, SelfClosingTagToken:
+		var b strings.Builder
+		b.WriteString("<")
+		b.WriteString(t.Data)
+		for _, a := range t.Attr {
+			b.WriteString(" ")
+			b.WriteString(a.Key)
+			b.WriteString(`="`)
+			b.WriteString(html.EscapeString(a.Val))
+			b.WriteString(`"`)
+		}
+		if t.Type == SelfClosingTagToken {
+			b.WriteString("/")
+		}
+		b.WriteString(">")
+		return b.String()
+	case EndTagToken:
+		return "</" + t.Data + ">"
+	case CommentToken:
+		return "<!--" + t.Data + "-->"
+	case DoctypeToken:
+		return "<!" + t.Data + ">"
+	default:
+		return ""
+	}
+}
+
+// rawTextElements hold text that is not markup and runs until the matching end
+// tag. The content of escapableRawTextElements may still contain entities.
+var (
+	rawTextElements = map[string]bool{
+		"script":   true,
+		"style":    true,
+		"iframe":   true,
+		"xmp":      true,
+		"noembed":  true,
+		"noframes": true,
+	}
+	escapableRawTextElements = map[string]bool{
+		"textarea": true,
+		"title":    true,
+	}
+)
+
+// Tokenize splits input into a sequence of tokens.
+func Tokenize(input string) []Token {
+	var tokens []Token
+	text := func(s string) {
+		if s == "" {
+			return
+		}
+		tokens = append(tokens, Token{Type: TextToken, Data: html.UnescapeString(s)})
+	}
+
+	i := 0
+	for i < len(input) {
+		lt := strings.IndexByte(input[i:], '<')
+		if lt == -1 {
+			text(input[i:])
+			break
+		}
+		text(input[i : i+lt])
+		i += lt
+
+		rest := input[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end == -1 {
+				tokens = append(tokens, Token{Type: CommentToken, Data: rest[4:]})
+				i = len(input)
+				continue
+			}
+			tokens = append(tokens, Token{Type: CommentToken, Data: rest[4 : 4+end]})
+			i += 4 + end + 3
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			tokens = append(tokens, Token{Type: DoctypeToken, Data: rest[2:end]})
+			i += end + 1
+		case len(rest) > 2 && rest[1] == '/' && isLetter(rest[2]):
+			name, _ := readName(rest[2:])
+			end := strings.IndexByte(rest, '>')
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			tokens = append(tokens, Token{Type: EndTagToken, Data: strings.ToLower(name)})
+			i += end + 1
+		case len(rest) > 1 && isLetter(rest[1]):
+			tok, n := readTag(rest)
+			tokens = append(tokens, tok)
+			i += n
+			if tok.Type != StartTagToken {
+				continue
+			}
+			if rawTextElements[tok.Data] || escapableRawTextElements[tok.Data] {
+				body, n := readRawText(input[i:], tok.Data)
+				if body != "" {
+					if escapableRawTextElements[tok.Data] {
+						body = html.UnescapeString(body)
+					}
+					tokens = append(tokens, Token{Type: TextToken, Data: body})
+				}
+				i += n
+			}
+		default:
+			text("<")
+			i++
+		}
+	}
+	return mergeText(tokens)
+}
+
+// readTag reads a start tag from the beginning of s, which must start with '<'
+// followed by a letter, and returns it along with the number of bytes read.
+func readTag(s string) (Token, int) {
+	name, n := readName(s[1:])
+	tok := Token{Type: StartTagToken, Data: strings.ToLower(name)}
+	i := 1 + n
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '>':
+			return tok, i + 1
+		case c == '/' && i+1 < len(s) && s[i+1] == '>':
+			tok.Type = SelfClosingTagToken
+			return tok, i + 2
+		case isSpace(c) || c == '/':
+			i++
+		default:
+			attr, n := readAttr(s[i:])
+			i += n
+			if attr.Key != "" {
+				tok.Attr = append(tok.Attr, attr)
+			}
+		}
+	}
+	return tok, len(s)
+}
+
+func readAttr(s string) (Attribute, int) {
+	i := 0
+	for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	if i == 0 {
+		return Attribute{}, 1
+	}
+	attr := Attribute{Key: strings.ToLower(s[:i])}
+
+	j := i
+	for j < len(s) && isSpace(s[j]) {
+		j++
+	}
+	if j >= len(s) || s[j] != '=' {
+		return attr, i
+	}
+	j++
+	for j < len(s) && isSpace(s[j]) {
+		j++
+	}
+	if j >= len(s) {
+		return attr, j
+	}
+
+	if q := s[j]; q == '"' || q == '\'' {
+		end := strings.IndexByte(s[j+1:], q)
+		if end == -1 {
+			attr.Val = html.UnescapeString(s[j+1:])
+			return attr, len(s)
+		}
+		attr.Val = html.UnescapeString(s[j+1 : j+1+end])
+		return attr, j + 1 + end + 1
+	}
+
+	start := j
+	for j < len(s) && !isSpace(s[j]) && s[j] != '>' {
+		j++
+	}
+	attr.Val = html.UnescapeString(s[start:j])
+	return attr, j
+}
+
+// readRawText returns everything up to the end tag for the named element and
+// the number of bytes consumed, not including the end tag itself.
+func readRawText(s, name string) (string, int) {
+	end := strings.Index(strings.ToLower(s), "</"+name)
+	if end == -1 {
+		return s, len(s)
+	}
+	return s[:end], end
+}
+
+func readName(s string) (string, int) {
+	i := 0
+	for i < len(s) && (isLetter(s[i]) || isDigit(s[i]) || s[i] == '-' || s[i] == ':' || s[i] == '_') {
+		i++
+	}
+	return s[:i], i
+}
+
+// mergeText joins adjacent text tokens, which are produced when a stray '<'
+// turns out not to start a tag.
+func mergeText(tokens []Token) []Token {
+	var merged []Token
+	for _, tok := range tokens {
+		if n := len(merged); n > 0 && tok.Type == TextToken && merged[n-1].Type == TextToken {
+			merged[n-1].Data += tok.Data
+			continue
+		}
+		merged = append(merged, tok)
+	}
+	return merged
+}
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,73 @@
+package markup_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/haleyrc/rss/markup"
+)
+
+func TestTokenize(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  []markup.Token
+	}{
+		{
+			name:  "text",
+			input: `fish &amp; chips`,
+			want: []markup.Token{
+				{Type: markup.TextToken, Data: "fish & chips"},
+			},
+		},
+		{
+			name:  "attributes",
+			input: `<A HREF="/x?a=1&amp;b=2" class=link data-x='y' hidden>go</A>`,
+			want: []markup.Token{
+				{Type: markup.StartTagToken, Data: "a", Attr: []markup.Attribute{
+					{Key: "href", Val: "/x?a=1&b=2"},
+					{Key: "class", Val: "link"},
+					{Key: "data-x", Val: "y"},
+					{Key: "hidden", Val: ""},
+				}},
+				{Type: markup.TextToken, Data: "go"},
+				{Type: markup.EndTagToken, Data: "a"},
+			},
+		},
+		{
+			name:  "self closing and comments",
+			input: `<!DOCTYPE html><br/><!-- note -->`,
+			want: []markup.Token{
+				{Type: markup.DoctypeToken, Data: "DOCTYPE html"},
+				{Type: markup.SelfClosingTagToken, Data: "br"},
+				{Type: markup.CommentToken, Data: " note "},
+			},
+		},
+		{
+			name:  "raw text",
+			input: `<script>if (a < b) { x = "</p>" }</script>after`,
+			want: []markup.Token{
+				{Type: markup.StartTagToken, Data: "script"},
+				{Type: markup.TextToken, Data: `if (a < b) { x = "</p>" }`},
+				{Type: markup.EndTagToken, Data: "script"},
+				{Type: markup.TextToken, Data: "after"},
+			},
+		},
+		{
+			name:  "stray less than",
+			input: `1 < 2`,
+			want: []markup.Token{
+				{Type: markup.TextToken, Data: "1 < 2"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := markup.Tokenize(tc.input)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("wanted %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
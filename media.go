@@ -0,0 +1,220 @@
+package rss
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss/markup"
+	"github.com/haleyrc/rss/parser"
+)
+
+// Media describes the images, audio and video attached to an item through the
+// Media RSS namespace. Groups are flattened into their contents, with any
+// group level description, thumbnails and credits applied to each rendition.
+type Media struct {
+	Contents    []MediaContent   `json:"contents,omitempty"`
+	Thumbnails  []MediaThumbnail `json:"thumbnails,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Credits     []MediaCredit    `json:"credits,omitempty"`
+}
+
+type MediaContent struct {
+	URL         string           `json:"url"`
+	Type        string           `json:"type,omitempty"`
+	Medium      string           `json:"medium,omitempty"`
+	FileSize    int64            `json:"fileSize,omitempty"`
+	Duration    int64            `json:"duration,omitempty"`
+	Width       int              `json:"width,omitempty"`
+	Height      int              `json:"height,omitempty"`
+	IsDefault   bool             `json:"isDefault,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Thumbnails  []MediaThumbnail `json:"thumbnails,omitempty"`
+	Credits     []MediaCredit    `json:"credits,omitempty"`
+}
+
+type MediaThumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+type MediaCredit struct {
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+// Value stores Media as JSON so that it can be kept in a single column.
+func (m Media) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan loads Media stored by Value.
+func (m *Media) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = Media{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return errors.Errorf("can not scan %T into Media", src)
+	}
+}
+
+// IsZero reports whether the item had no media elements.
+func (m Media) IsZero() bool {
+	return len(m.Contents) == 0 && len(m.Thumbnails) == 0 && m.Description == "" && len(m.Credits) == 0
+}
+
+func newMedia(in parser.Media, base string) Media {
+	m := Media{
+		Thumbnails:  newMediaThumbnails(in.MediaThumbnails, base),
+		Description: strings.TrimSpace(in.MediaDescription),
+		Credits:     newMediaCredits(in.MediaCredits),
+	}
+	for _, c := range in.MediaContents {
+		if content, ok := newMediaContent(c, base); ok {
+			m.Contents = append(m.Contents, content)
+		}
+	}
+	for _, g := range in.MediaGroups {
+		for _, c := range g.Contents {
+			content, ok := newMediaContent(c, base)
+			if !ok {
+				continue
+			}
+			if content.Title == "" {
+				content.Title = strings.TrimSpace(g.Title)
+			}
+			if content.Description == "" {
+				content.Description = strings.TrimSpace(g.Description)
+			}
+			if len(content.Thumbnails) == 0 {
+				content.Thumbnails = newMediaThumbnails(g.Thumbnails, base)
+			}
+			if len(content.Credits) == 0 {
+				content.Credits = newMediaCredits(g.Credits)
+			}
+			m.Contents = append(m.Contents, content)
+		}
+		if m.Description == "" {
+			m.Description = strings.TrimSpace(g.Description)
+		}
+	}
+	return m
+}
+
+func newMediaContent(c parser.MediaContent, base string) (MediaContent, bool) {
+	url := parser.ResolveURL(base, c.URL)
+	if url == "" {
+		return MediaContent{}, false
+	}
+	duration, _ := strconv.ParseFloat(strings.TrimSpace(c.Duration), 64)
+	return MediaContent{
+		URL:         url,
+		Type:        strings.TrimSpace(c.Type),
+		Medium:      strings.TrimSpace(c.Medium),
+		FileSize:    atoi64(c.FileSize),
+		Duration:    int64(duration),
+		Width:       int(atoi64(c.Width)),
+		Height:      int(atoi64(c.Height)),
+		IsDefault:   strings.TrimSpace(c.IsDefault) == "true",
+		Title:       strings.TrimSpace(c.Title),
+		Description: strings.TrimSpace(c.Description),
+		Thumbnails:  newMediaThumbnails(c.Thumbnails, base),
+		Credits:     newMediaCredits(c.Credits),
+	}, true
+}
+
+func newMediaThumbnails(in []parser.MediaThumbnail, base string) []MediaThumbnail {
+	var thumbnails []MediaThumbnail
+	for _, t := range in {
+		url := parser.ResolveURL(base, t.URL)
+		if url == "" {
+			continue
+		}
+		thumbnails = append(thumbnails, MediaThumbnail{
+			URL:    url,
+			Width:  int(atoi64(t.Width)),
+			Height: int(atoi64(t.Height)),
+		})
+	}
+	return thumbnails
+}
+
+func newMediaCredits(in []parser.MediaCredit) []MediaCredit {
+	var credits []MediaCredit
+	for _, c := range in {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			continue
+		}
+		credits = append(credits, MediaCredit{Name: name, Role: strings.TrimSpace(c.Role)})
+	}
+	return credits
+}
+
+// leadImage picks the image that best represents an item in a list view. Media
+// thumbnails are preferred, largest first, followed by image media content,
+// image enclosures, the podcast episode artwork and finally the first image
+// in the item content.
+func leadImage(item *Item) string {
+	if url := largestThumbnail(item.Media.Thumbnails); url != "" {
+		return url
+	}
+	for _, c := range item.Media.Contents {
+		if url := largestThumbnail(c.Thumbnails); url != "" {
+			return url
+		}
+	}
+	for _, c := range item.Media.Contents {
+		if c.Medium == "image" || strings.HasPrefix(c.Type, "image/") {
+			return c.URL
+		}
+	}
+	for _, e := range item.Enclosures {
+		if strings.HasPrefix(e.Type, "image/") {
+			return e.URL
+		}
+	}
+	if item.Podcast.Image != "" {
+		return item.Podcast.Image
+	}
+	for _, tok := range markup.Tokenize(item.Content) {
+		if tok.Type != markup.StartTagToken && tok.Type != markup.SelfClosingTagToken || tok.Data != "img" {
+			continue
+		}
+		if src, ok := tok.Get("src"); ok && src != "" {
+			return src
+		}
+	}
+	return ""
+}
+
+func largestThumbnail(thumbnails []MediaThumbnail) string {
+	best := -1
+	for i, t := range thumbnails {
+		if best == -1 || t.Width*t.Height > thumbnails[best].Width*thumbnails[best].Height {
+			best = i
+		}
+	}
+	if best == -1 {
+		return ""
+	}
+	return thumbnails[best].URL
+}
+
+func atoi64(s string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,90 @@
+package rss_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromChannelMedia(t *testing.T) {
+	testcases := []struct {
+		name        string
+		file        string
+		contents    int
+		description string
+		leadImage   string
+	}{
+		{
+			name:        "media group in atom",
+			file:        "youtube.xml",
+			contents:    1,
+			description: "In this video we build a feed reader.",
+			leadImage:   "https://i1.ytimg.com/vi/abc123/hqdefault.jpg",
+		},
+		{
+			name:      "media content in rss",
+			file:      "checkly.xml",
+			contents:  1,
+			leadImage: "https://blog.checklyhq.com/content/images/2019/04/money-shower-yoshitora-japanese-woodblock_1_614241d00d1c50a81398a3028fec37b1.jpg",
+		},
+		{
+			name:      "no media",
+			file:      "atom.xml",
+			contents:  0,
+			leadImage: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			xmlFeed, err := parser.LoadFile(filepath.Join("testdata", tc.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			feed, err := rss.NewFromChannel(xmlFeed.Channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(feed.Items) == 0 {
+				t.Fatalf("expected items, got none")
+			}
+
+			item := feed.Items[0]
+			if len(item.Media.Contents) != tc.contents {
+				t.Fatalf("expected %d media contents, got %d", tc.contents, len(item.Media.Contents))
+			}
+			if item.Media.Description != tc.description {
+				t.Errorf("expected description %q, got %q", tc.description, item.Media.Description)
+			}
+			if item.LeadImage != tc.leadImage {
+				t.Errorf("expected lead image %q, got %q", tc.leadImage, item.LeadImage)
+			}
+		})
+	}
+}
+
+func TestMediaScan(t *testing.T) {
+	want := rss.Media{
+		Contents: []rss.MediaContent{{URL: "https://example.com/video.mp4", Width: 640}},
+		Credits:  []rss.MediaCredit{{Name: "Jane Doe", Role: "author"}},
+	}
+
+	value, err := want.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got rss.Media
+	if err := got.Scan(value); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Contents) != 1 || got.Contents[0].URL != want.Contents[0].URL || got.Contents[0].Width != 640 {
+		t.Errorf("wanted %+v, got %+v", want, got)
+	}
+	if len(got.Credits) != 1 || got.Credits[0] != want.Credits[0] {
+		t.Errorf("wanted %+v, got %+v", want, got)
+	}
+}
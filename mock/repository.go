@@ -3,21 +3,30 @@ package mock
 import (
 	"errors"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/haleyrc/rss"
 )
 
 func NewRepository() rss.Repository {
 	return &repository{
-		feeds: make(map[int64]*rss.Feed),
-		items: make(map[int64]*rss.Item),
+		feeds:   make(map[int64]*rss.Feed),
+		items:   make(map[int64]*rss.Item),
+		aliases: make(map[string]int64),
+		subs:    make(map[int64]*rss.Subscription),
+		clouds:  make(map[int64]*rss.CloudRegistration),
 	}
 }
 
 type repository struct {
-	lastID int64
-	feeds  map[int64]*rss.Feed
-	items  map[int64]*rss.Item
+	lastID  int64
+	feeds   map[int64]*rss.Feed
+	items   map[int64]*rss.Item
+	aliases map[string]int64
+	subs    map[int64]*rss.Subscription
+	clouds  map[int64]*rss.CloudRegistration
 }
 
 func (r *repository) CreateFeed(feed *rss.Feed, items ...*rss.Item) error {
@@ -33,12 +42,139 @@ func (r *repository) CreateFeed(feed *rss.Feed, items ...*rss.Item) error {
 	return nil
 }
 
+func (r *repository) GetFeed(id int64) (*rss.Feed, error) {
+	feed, ok := r.feeds[id]
+	if !ok {
+		return nil, rss.ErrFeedNotFound
+	}
+	return feed, nil
+}
+
+func (r *repository) FindFeedByURL(url string) (*rss.Feed, error) {
+	if id, ok := r.aliases[url]; ok {
+		return r.GetFeed(id)
+	}
+	for _, feed := range r.feeds {
+		if feed.URL == url {
+			return feed, nil
+		}
+	}
+	return nil, rss.ErrFeedNotFound
+}
+
+func (r *repository) MoveFeed(feed *rss.Feed, url string) error {
+	stored, ok := r.feeds[feed.ID]
+	if !ok {
+		return errors.New("not found")
+	}
+	if feed.URL != "" {
+		r.aliases[feed.URL] = feed.ID
+	}
+	delete(r.aliases, url)
+
+	now := time.Now()
+	feed.URL = url
+	feed.MovedAt = &now
+	feed.Aliases = []string{}
+	for alias, id := range r.aliases {
+		if id == feed.ID {
+			feed.Aliases = append(feed.Aliases, alias)
+		}
+	}
+	sort.Strings(feed.Aliases)
+
+	stored.URL = feed.URL
+	stored.MovedAt = feed.MovedAt
+	stored.Aliases = feed.Aliases
+	return nil
+}
+
+func (r *repository) UpdateFeedCache(feed *rss.Feed) error {
+	if _, ok := r.feeds[feed.ID]; !ok {
+		return errors.New("not found")
+	}
+	r.feeds[feed.ID].ETag = feed.ETag
+	r.feeds[feed.ID].LastModified = feed.LastModified
+	r.feeds[feed.ID].NextFetch = feed.NextFetch
+	r.feeds[feed.ID].Schedule = feed.Schedule
+	r.feeds[feed.ID].Report = feed.Report
+	r.feeds[feed.ID].RejectedSelfLink = feed.RejectedSelfLink
+	r.feeds[feed.ID].FetchedAt = feed.FetchedAt
+	return nil
+}
+
 func (r *repository) RemoveFeed(id int64) error {
 	for iid, item := range r.items {
 		if item.FeedID == id {
 			delete(r.items, iid)
 		}
 	}
+	delete(r.subs, id)
+	delete(r.clouds, id)
+	return nil
+}
+
+func (r *repository) SaveSubscription(sub *rss.Subscription) error {
+	stored := *sub
+	r.subs[sub.FeedID] = &stored
+	return nil
+}
+
+func (r *repository) GetSubscription(feedID int64) (*rss.Subscription, error) {
+	sub, ok := r.subs[feedID]
+	if !ok {
+		return nil, rss.ErrSubscriptionNotFound
+	}
+	found := *sub
+	return &found, nil
+}
+
+func (r *repository) ListSubscriptionsExpiring(before time.Time) ([]*rss.Subscription, error) {
+	subs := []*rss.Subscription{}
+	for _, sub := range r.subs {
+		if sub.State == rss.SubscriptionActive && sub.LeaseExpires != nil && sub.LeaseExpires.Before(before) {
+			found := *sub
+			subs = append(subs, &found)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].LeaseExpires.Before(*subs[j].LeaseExpires) })
+	return subs, nil
+}
+
+func (r *repository) RemoveSubscription(feedID int64) error {
+	delete(r.subs, feedID)
+	return nil
+}
+
+func (r *repository) SaveCloudRegistration(reg *rss.CloudRegistration) error {
+	stored := *reg
+	r.clouds[reg.FeedID] = &stored
+	return nil
+}
+
+func (r *repository) GetCloudRegistration(feedID int64) (*rss.CloudRegistration, error) {
+	reg, ok := r.clouds[feedID]
+	if !ok {
+		return nil, rss.ErrRegistrationNotFound
+	}
+	found := *reg
+	return &found, nil
+}
+
+func (r *repository) ListCloudRegistrationsBefore(before time.Time) ([]*rss.CloudRegistration, error) {
+	regs := []*rss.CloudRegistration{}
+	for _, reg := range r.clouds {
+		if reg.RegisteredAt != nil && reg.RegisteredAt.Before(before) {
+			found := *reg
+			regs = append(regs, &found)
+		}
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].RegisteredAt.Before(*regs[j].RegisteredAt) })
+	return regs, nil
+}
+
+func (r *repository) RemoveCloudRegistration(feedID int64) error {
+	delete(r.clouds, feedID)
 	return nil
 }
 
@@ -110,7 +246,39 @@ func (r *repository) ListItems(limit int) ([]*rss.Item, error) {
 	for _, item := range r.items {
 		items = append(items, item)
 	}
-	if limit < len(items) {
+	if limit > 0 && limit < len(items) {
+		return items[:limit], nil
+	}
+	return items, nil
+}
+
+func (r *repository) ListItemsByCategory(category string, limit int) ([]*rss.Item, error) {
+	var items []*rss.Item
+	for _, item := range r.items {
+		for _, c := range item.Categories {
+			if strings.EqualFold(c.Name, category) {
+				items = append(items, item)
+				break
+			}
+		}
+	}
+	if limit > 0 && limit < len(items) {
+		return items[:limit], nil
+	}
+	return items, nil
+}
+
+func (r *repository) ListItemsByAuthor(author string, limit int) ([]*rss.Item, error) {
+	var items []*rss.Item
+	for _, item := range r.items {
+		for _, a := range item.Authors {
+			if strings.EqualFold(a.Name, author) || strings.EqualFold(a.Email, author) {
+				items = append(items, item)
+				break
+			}
+		}
+	}
+	if limit > 0 && limit < len(items) {
 		return items[:limit], nil
 	}
 	return items, nil
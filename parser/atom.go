@@ -0,0 +1,236 @@
+package parser
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+)
+
+// atomFeed is the wire representation of an Atom <feed> document. It is
+// never returned to callers; toFeed maps it onto the same Channel and Item
+// types that are produced for RSS so that the rest of the application does not
+// need to care which format a feed was published in. Elements are matched in
+// any namespace, so that Atom 0.3 feeds and feeds that forgot to declare the
+// Atom namespace are read too; Atom 0.3 only renamed a few of them.
+type atomFeed struct {
+	XMLName    xml.Name       `xml:"feed"`
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title      atomText       `xml:"title"`
+	Subtitle   atomText       `xml:"subtitle"`
+	Tagline    atomText       `xml:"tagline"`
+	Links      []atomLink     `xml:"link"`
+	Icon       string         `xml:"icon"`
+	Logo       string         `xml:"logo"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Entries    []atomEntry    `xml:"entry"`
+
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+// atomEntry embeds Media first: the decoder gives an element to the first
+// field that matches it, so media:content is kept as Media RSS rather than
+// being taken for the entry's content.
+type atomEntry struct {
+	Media
+
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Issued     string         `xml:"issued"`
+	Updated    string         `xml:"updated"`
+	Modified   string         `xml:"modified"`
+	Authors    []atomPerson   `xml:"author"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomLink struct {
+	Href     string `xml:"href,attr"`
+	Rel      string `xml:"rel,attr"`
+	Type     string `xml:"type,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Title    string `xml:"title,attr"`
+	Length   string `xml:"length,attr"`
+}
+
+// atomCategory identifies a category by its term. The label is only meant
+// for display, so the term is what we keep.
+type atomCategory struct {
+	Term   string `xml:"term,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Label  string `xml:"label,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+	URI   string `xml:"uri"`
+}
+
+// atomText holds an Atom text construct. When the type is "xhtml" the content
+// is wrapped in a <div> which we keep as raw markup rather than flattening it.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+// Text returns the construct as plain text, removing the markup of html and
+// xhtml constructs.
+func (t atomText) Text() string {
+	if t.Type == "html" || t.Type == "xhtml" {
+		return markupText(t.String())
+	}
+	return t.String()
+}
+
+func loadAtom(b []byte) (Feed, error) {
+	var af atomFeed
+	if err := newDecoder(b).Decode(&af); err != nil {
+		return Feed{}, err
+	}
+	return af.toFeed(), nil
+}
+
+func (af atomFeed) toFeed() Feed {
+	base := af.Base
+	c := Channel{
+		Title:       af.Title.Text(),
+		Description: firstNonEmpty(af.Subtitle.String(), af.Tagline.String()),
+		Link:        ResolveURL(base, alternateLink(af.Links)),
+		Image:       ResolveURL(base, firstNonEmpty(af.Logo, af.Icon)),
+		Base:        base,
+		Categories:  atomCategories(af.Categories),
+		Authors:     atomPeople(af.Authors),
+
+		UpdatePeriod:    af.UpdatePeriod,
+		UpdateFrequency: af.UpdateFrequency,
+	}
+	for _, l := range af.Links {
+		if l.Rel != "" && l.Rel != "alternate" {
+			c.Links = append(c.Links, Link{Href: ResolveURL(base, l.Href), Rel: l.Rel, Type: l.Type})
+		}
+	}
+	for _, e := range af.Entries {
+		entryBase := inheritBase(base, e.Base)
+		item := Item{
+			GUID:            GUID{Value: strings.TrimSpace(e.ID), IsPermaLink: "false"},
+			Title:           e.Title.Text(),
+			Link:            ResolveURL(entryBase, alternateLink(e.Links)),
+			PublicationDate: strings.TrimSpace(firstNonEmpty(e.Published, e.Issued, e.Updated, e.Modified)),
+			Description:     e.Summary.String(),
+			Content:         e.Content.String(),
+			Authors:         atomPeople(e.Authors),
+			Categories:      atomCategories(e.Categories),
+			Media:           e.Media,
+		}
+		// Entries without an author inherit those of the feed.
+		if len(item.Authors) == 0 {
+			item.Authors = c.Authors
+		}
+		item.Author = firstName(item.Authors)
+		if entryBase != base {
+			item.Base = entryBase
+		}
+		for _, l := range e.Links {
+			if l.Rel == "enclosure" && l.Href != "" {
+				item.Enclosures = append(item.Enclosures, Enclosure{
+					URL:    ResolveURL(entryBase, l.Href),
+					Type:   l.Type,
+					Length: l.Length,
+				})
+			}
+		}
+		c.Items = append(c.Items, item)
+	}
+	return Feed{Format: FormatAtom, Channel: c}
+}
+
+// alternateLink returns the href of the link that points to the HTML
+// representation of a feed or entry. Per RFC 4287 a link without a rel
+// attribute is an alternate link. If there is no alternate link we fall back to
+// the first link with an href so that the item at least points somewhere.
+func alternateLink(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	for _, l := range links {
+		if l.Href != "" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+func atomCategories(in []atomCategory) []Category {
+	var categories []Category
+	for _, c := range in {
+		// Atom requires a term; other elements named category, such as
+		// media:category, have none.
+		if strings.TrimSpace(c.Term) == "" {
+			continue
+		}
+		categories = append(categories, Category{Name: c.Term, Domain: c.Scheme})
+	}
+	return categories
+}
+
+func atomPeople(in []atomPerson) []Person {
+	var people []Person
+	for _, p := range in {
+		people = append(people, Person{Name: p.Name, Email: p.Email, URL: p.URI})
+	}
+	return people
+}
+
+// ResolveURL resolves ref against base. If either value is empty or can not be
+// parsed, ref is returned unchanged.
+func ResolveURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	base = strings.TrimSpace(base)
+	if ref == "" || base == "" {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// inheritBase returns the base URL in scope for an element with the xml:base
+// attribute base inside an element whose base URL is parent.
+func inheritBase(parent, base string) string {
+	if strings.TrimSpace(base) == "" {
+		return parent
+	}
+	return ResolveURL(parent, base)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
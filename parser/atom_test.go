@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  Format
+		err   bool
+	}{
+		{
+			name:  "rss",
+			input: `<?xml version="1.0"?><rss version="2.0"><channel></channel></rss>`,
+			want:  FormatRSS,
+		},
+		{
+			name:  "atom",
+			input: `<?xml version="1.0"?><!-- comment --><feed xmlns="http://www.w3.org/2005/Atom"></feed>`,
+			want:  FormatAtom,
+		},
+		{
+			name:  "rdf",
+			input: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"></rdf:RDF>`,
+			want:  FormatRDF,
+		},
+		{
+			name:  "json",
+			input: "\ufeff  {\"version\": \"https://jsonfeed.org/version/1.1\"}",
+			want:  FormatJSON,
+		},
+		{
+			name:  "unknown root",
+			input: `<html><body></body></html>`,
+			err:   true,
+		},
+		{
+			name:  "empty",
+			input: ``,
+			err:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DetectFormat([]byte(tc.input))
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected format %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadAtom(t *testing.T) {
+	feed, err := LoadFile(filepath.Join("..", "testdata", "atom.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if feed.Format != FormatAtom {
+		t.Errorf("expected format %q, got %q", FormatAtom, feed.Format)
+	}
+
+	c := feed.Channel
+	if want := "Example Atom Feed"; c.Title != want {
+		t.Errorf("expected title %q, got %q", want, c.Title)
+	}
+	if want := "A feed used for testing the Atom decoder."; c.Description != want {
+		t.Errorf("expected description %q, got %q", want, c.Description)
+	}
+	if want := "https://example.org/"; c.Link != want {
+		t.Errorf("expected link %q, got %q", want, c.Link)
+	}
+	if want := "https://example.org/images/logo.png"; c.Image != want {
+		t.Errorf("expected image %q, got %q", want, c.Image)
+	}
+
+	if len(c.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(c.Items))
+	}
+
+	first := c.Items[0]
+	if want := "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a"; first.GUID.Value != want {
+		t.Errorf("expected guid %q, got %q", want, first.GUID.Value)
+	}
+	if want := "https://example.org/2019/04/08/atom-powered-robots"; first.Link != want {
+		t.Errorf("expected link %q, got %q", want, first.Link)
+	}
+	if want := "2019-04-08T18:30:02Z"; first.PublicationDate != want {
+		t.Errorf("expected publication date %q, got %q", want, first.PublicationDate)
+	}
+	if want := "Jane Doe"; first.Author != want {
+		t.Errorf("expected author %q, got %q", want, first.Author)
+	}
+	if want := "Some text."; first.Description != want {
+		t.Errorf("expected description %q, got %q", want, first.Description)
+	}
+	if !strings.Contains(first.Content, "<p>This is the entry content.</p>") {
+		t.Errorf("expected content to contain the xhtml body, got %q", first.Content)
+	}
+
+	second := c.Items[1]
+	if want := "https://other.example.org/blog/second-entry"; second.Link != want {
+		t.Errorf("expected link %q, got %q", want, second.Link)
+	}
+	if want := "2019-04-07T08:15:00+02:00"; second.PublicationDate != want {
+		t.Errorf("expected publication date %q, got %q", want, second.PublicationDate)
+	}
+	if want := "John Smith"; second.Author != want {
+		t.Errorf("expected author %q, got %q", want, second.Author)
+	}
+	if want := "<p>Escaped <em>HTML</em> summary.</p>"; second.Description != want {
+		t.Errorf("expected description %q, got %q", want, second.Description)
+	}
+}
+
+func TestLoadAtomNamespaces(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		content string
+		media   int
+	}{
+		{
+			name: "atom 1.0",
+			input: `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title><subtitle>D</subtitle><link href="https://example.com/"/>` +
+				`<entry><title>I</title><link href="https://example.com/i"/><updated>2019-04-01T10:00:00Z</updated><content>C</content></entry></feed>`,
+			content: "C",
+		},
+		{
+			name: "no namespace",
+			input: `<feed><title>T</title><subtitle>D</subtitle><link href="https://example.com/"/>` +
+				`<entry><title>I</title><link href="https://example.com/i"/><updated>2019-04-01T10:00:00Z</updated><content>C</content></entry></feed>`,
+			content: "C",
+		},
+		{
+			name: "atom 0.3",
+			input: `<feed version="0.3" xmlns="http://purl.org/atom/ns#"><title>T</title><tagline>D</tagline><link rel="alternate" type="text/html" href="https://example.com/"/>` +
+				`<entry><title>I</title><link rel="alternate" type="text/html" href="https://example.com/i"/><issued>2019-04-01T10:00:00Z</issued><modified>2019-04-02T10:00:00Z</modified><content mode="escaped">C</content></entry></feed>`,
+			content: "C",
+		},
+		{
+			name: "media content",
+			input: `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/"><title>T</title><subtitle>D</subtitle><link href="https://example.com/"/>` +
+				`<entry><title>I</title><link href="https://example.com/i"/><updated>2019-04-01T10:00:00Z</updated><media:content url="https://example.com/i.jpg" medium="image"/><media:category>Art</media:category></entry></feed>`,
+			media: 1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if feed.Format != FormatAtom {
+				t.Errorf("expected format %q, got %q", FormatAtom, feed.Format)
+			}
+			c := feed.Channel
+			if c.Title != "T" {
+				t.Errorf("expected title %q, got %q", "T", c.Title)
+			}
+			if c.Description != "D" {
+				t.Errorf("expected description %q, got %q", "D", c.Description)
+			}
+			if c.Link != "https://example.com/" {
+				t.Errorf("expected link %q, got %q", "https://example.com/", c.Link)
+			}
+			if len(c.Items) != 1 {
+				t.Fatalf("expected %d item, got %d", 1, len(c.Items))
+			}
+			item := c.Items[0]
+			if item.Title != "I" {
+				t.Errorf("expected title %q, got %q", "I", item.Title)
+			}
+			if item.Link != "https://example.com/i" {
+				t.Errorf("expected link %q, got %q", "https://example.com/i", item.Link)
+			}
+			if item.PublicationDate != "2019-04-01T10:00:00Z" {
+				t.Errorf("expected publication date %q, got %q", "2019-04-01T10:00:00Z", item.PublicationDate)
+			}
+			if item.Content != tc.content {
+				t.Errorf("expected content %q, got %q", tc.content, item.Content)
+			}
+			if len(item.MediaContents) != tc.media {
+				t.Errorf("expected %d media contents, got %d", tc.media, len(item.MediaContents))
+			}
+			if len(item.Categories) != 0 {
+				t.Errorf("expected no categories, got %+v", item.Categories)
+			}
+		})
+	}
+}
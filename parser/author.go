@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"strings"
+)
+
+// Person is someone credited with a channel or item. Atom and JSON Feed
+// describe people in structured form, whereas RSS only has a line of text
+// which parsePerson picks apart.
+type Person struct {
+	Name   string
+	Email  string
+	URL    string
+	Avatar string
+}
+
+// parsePerson reads a person from an RSS byline. The specification asks for
+// "email (Name)", but "Name <email>", a bare address and a bare name are all
+// common too.
+func parsePerson(s string) Person {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "("); i != -1 && strings.HasSuffix(s, ")") {
+		if email := strings.TrimSpace(s[:i]); isEmail(email) {
+			return Person{Name: strings.TrimSpace(s[i+1 : len(s)-1]), Email: email}
+		}
+	}
+	if i := strings.LastIndex(s, "<"); i != -1 && strings.HasSuffix(s, ">") {
+		if email := strings.TrimSpace(s[i+1 : len(s)-1]); isEmail(email) {
+			return Person{Name: strings.Trim(strings.TrimSpace(s[:i]), `"`), Email: email}
+		}
+	}
+	if isEmail(s) {
+		return Person{Email: strings.TrimPrefix(s, "mailto:")}
+	}
+	return Person{Name: s}
+}
+
+// isEmail reports whether s looks enough like an email address to be treated
+// as one.
+func isEmail(s string) bool {
+	s = strings.TrimPrefix(s, "mailto:")
+	at := strings.Index(s, "@")
+	return at > 0 && at < len(s)-1 && !strings.ContainsAny(s, " \t<>()")
+}
+
+// firstName returns the name of the first person who has one.
+func firstName(people []Person) string {
+	for _, p := range people {
+		if name := strings.TrimSpace(p.Name); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// rssAuthors fills in the structured authors of an RSS channel and its items
+// from their bylines. The managing editor is responsible for the content of a
+// channel, so the webmaster is only used when there is no editor.
+func (c *Channel) rssAuthors() {
+	if byline := firstNonEmpty(c.ManagingEditor, c.WebMaster); byline != "" {
+		c.Authors = []Person{parsePerson(byline)}
+	}
+	for i := range c.Items {
+		item := &c.Items[i]
+		for _, byline := range append([]string{item.Author}, item.Creators...) {
+			if strings.TrimSpace(byline) == "" {
+				continue
+			}
+			item.Authors = append(item.Authors, parsePerson(byline))
+		}
+	}
+}
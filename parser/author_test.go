@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePerson(t *testing.T) {
+	testcases := []struct {
+		input string
+		want  Person
+	}{
+		{input: "jo@example.com (Jo Bloggs)", want: Person{Name: "Jo Bloggs", Email: "jo@example.com"}},
+		{input: "Jo Bloggs <jo@example.com>", want: Person{Name: "Jo Bloggs", Email: "jo@example.com"}},
+		{input: `"Jo Bloggs" <jo@example.com>`, want: Person{Name: "Jo Bloggs", Email: "jo@example.com"}},
+		{input: "jo@example.com", want: Person{Email: "jo@example.com"}},
+		{input: "mailto:jo@example.com", want: Person{Email: "jo@example.com"}},
+		{input: "Jo Bloggs", want: Person{Name: "Jo Bloggs"}},
+		{input: "Jo Bloggs (Editor)", want: Person{Name: "Jo Bloggs (Editor)"}},
+		{input: "Jo @ Example", want: Person{Name: "Jo @ Example"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := parsePerson(tc.input); got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		channel []Person
+		item    []Person
+	}{
+		{
+			name:    "rss",
+			input:   `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><managingEditor>editor@example.com (The Editor)</managingEditor><webMaster>webmaster@example.com</webMaster><item><author>jo@example.com (Jo)</author><dc:creator>Sam</dc:creator></item></channel></rss>`,
+			channel: []Person{{Name: "The Editor", Email: "editor@example.com"}},
+			item:    []Person{{Name: "Jo", Email: "jo@example.com"}, {Name: "Sam"}},
+		},
+		{
+			name:    "rss webmaster",
+			input:   `<rss version="2.0"><channel><webMaster>webmaster@example.com</webMaster><item><title>I</title></item></channel></rss>`,
+			channel: []Person{{Email: "webmaster@example.com"}},
+		},
+		{
+			name:    "atom",
+			input:   `<feed xmlns="http://www.w3.org/2005/Atom"><author><name>Jo</name><email>jo@example.com</email><uri>http://example.com/jo</uri></author><entry><title>I</title></entry></feed>`,
+			channel: []Person{{Name: "Jo", Email: "jo@example.com", URL: "http://example.com/jo"}},
+			item:    []Person{{Name: "Jo", Email: "jo@example.com", URL: "http://example.com/jo"}},
+		},
+		{
+			name:    "json",
+			input:   `{"version": "https://jsonfeed.org/version/1.1", "title": "T", "authors": [{"name": "Jo"}], "items": [{"id": "1", "authors": [{"name": "Sam", "url": "http://example.com/sam", "avatar": "http://example.com/sam.png"}]}]}`,
+			channel: []Person{{Name: "Jo"}},
+			item:    []Person{{Name: "Sam", URL: "http://example.com/sam", Avatar: "http://example.com/sam.png"}},
+		},
+		{
+			name:  "rdf",
+			input: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>T</title></channel><item><dc:creator>Jo</dc:creator></item></rdf:RDF>`,
+			item:  []Person{{Name: "Jo"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(feed.Channel.Authors, tc.channel) {
+				t.Errorf("expected channel authors %+v, got %+v", tc.channel, feed.Channel.Authors)
+			}
+			if len(feed.Channel.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(feed.Channel.Items))
+			}
+			if got := feed.Channel.Items[0].Authors; !reflect.DeepEqual(got, tc.item) {
+				t.Errorf("expected item authors %+v, got %+v", tc.item, got)
+			}
+		})
+	}
+}
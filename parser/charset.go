@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+var reXMLEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*["'])([^"']*)(["'])`)
+
+// toUTF8 converts a feed document to UTF-8 so that the decoders never have to
+// deal with other character sets. The encoding is taken from, in order of
+// precedence, a byte order mark, the charset parameter of the HTTP Content-Type
+// header and the XML declaration. Publishers frequently get the declaration
+// wrong, so documents that claim to be UTF-8 but aren't are read as
+// windows-1252, and documents that claim a Latin character set but are valid
+// UTF-8 are left alone. The XML declaration of the result is rewritten to say
+// UTF-8 so that it agrees with the content.
+func toUTF8(b []byte, contentType string) ([]byte, error) {
+	var out []byte
+	var err error
+	switch {
+	case bytes.HasPrefix(b, utf8BOM):
+		out = b[len(utf8BOM):]
+	case bytes.HasPrefix(b, utf16LEBOM):
+		out, err = decode(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), b[len(utf16LEBOM):])
+	case bytes.HasPrefix(b, utf16BEBOM):
+		out, err = decode(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), b[len(utf16BEBOM):])
+	default:
+		label := charsetFromContentType(contentType)
+		if label == "" {
+			label = declaredEncoding(b)
+		}
+		out, err = decodeLabel(b, label)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return declareUTF8(out), nil
+}
+
+func decodeLabel(b []byte, label string) ([]byte, error) {
+	enc, name := charset.Lookup(label)
+	switch {
+	case label == "" || name == "utf-8" || enc == nil:
+		if utf8.Valid(b) {
+			return b, nil
+		}
+		return decode(charmap.Windows1252, b)
+	case strings.HasPrefix(name, "utf-16"):
+		// Without a byte order mark a document that declares UTF-16 has
+		// almost always been transcoded to UTF-8 by something upstream.
+		if utf8.Valid(b) {
+			return b, nil
+		}
+		return decode(enc, b)
+	case isSingleByteLatin(name) && !isASCII(b) && utf8.Valid(b):
+		return b, nil
+	default:
+		return decode(enc, b)
+	}
+}
+
+func decode(enc encoding.Encoding, b []byte) ([]byte, error) {
+	out, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not convert to utf-8")
+	}
+	return out, nil
+}
+
+// charsetFromContentType returns the charset parameter of an HTTP Content-Type
+// header, if there is one.
+func charsetFromContentType(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
+
+// declaredEncoding returns the encoding named in the XML declaration of b.
+func declaredEncoding(b []byte) string {
+	m := reXMLEncoding.FindSubmatch(b)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(string(m[2]))
+}
+
+func declareUTF8(b []byte) []byte {
+	loc := reXMLEncoding.FindSubmatchIndex(b)
+	if loc == nil {
+		return b
+	}
+	var out bytes.Buffer
+	out.Grow(len(b))
+	out.Write(b[:loc[4]])
+	out.WriteString("UTF-8")
+	out.Write(b[loc[5]:])
+	return out.Bytes()
+}
+
+func isSingleByteLatin(name string) bool {
+	return name == "windows-1252" || strings.HasPrefix(name, "iso-8859-")
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestLoadCharset(t *testing.T) {
+	const title = "Café — été"
+
+	document := func(declared, title string) []byte {
+		decl := `<?xml version="1.0"?>`
+		if declared != "" {
+			decl = `<?xml version="1.0" encoding="` + declared + `"?>`
+		}
+		return []byte(decl + `<rss version="2.0"><channel><title>` + title + `</title></channel></rss>`)
+	}
+	encode := func(enc encoding.Encoding, s string) string {
+		out, err := enc.NewEncoder().String(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return out
+	}
+
+	testcases := []struct {
+		name        string
+		input       []byte
+		contentType string
+		want        string
+	}{
+		{
+			name:  "utf-8",
+			input: document("UTF-8", title),
+			want:  title,
+		},
+		{
+			name:  "windows-1252",
+			input: document("windows-1252", encode(charmap.Windows1252, title)),
+			want:  title,
+		},
+		{
+			name:  "iso-8859-1",
+			input: document("ISO-8859-1", encode(charmap.ISO8859_1, "Café")),
+			want:  "Café",
+		},
+		{
+			name:  "shift_jis",
+			input: document("Shift_JIS", encode(japanese.ShiftJIS, "日本語")),
+			want:  "日本語",
+		},
+		{
+			name:  "koi8-r",
+			input: document("KOI8-R", encode(charmap.KOI8R, "Новости")),
+			want:  "Новости",
+		},
+		{
+			name:        "content type overrides declaration",
+			input:       document("UTF-8", encode(charmap.KOI8R, "Новости")),
+			contentType: "application/rss+xml; charset=koi8-r",
+			want:        "Новости",
+		},
+		{
+			name:  "utf-16 with bom",
+			input: []byte(encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), string(document("UTF-16", title)))),
+			want:  title,
+		},
+		{
+			name:  "utf-8 with bom",
+			input: append([]byte{0xEF, 0xBB, 0xBF}, document("", title)...),
+			want:  title,
+		},
+		{
+			name:  "declared utf-8 but windows-1252",
+			input: document("UTF-8", encode(charmap.Windows1252, title)),
+			want:  title,
+		},
+		{
+			name:  "declared iso-8859-1 but utf-8",
+			input: document("ISO-8859-1", title),
+			want:  title,
+		},
+		{
+			name:  "unknown charset",
+			input: document("x-made-up", title),
+			want:  title,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := load(tc.input, tc.contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if feed.Channel.Title != tc.want {
+				t.Errorf("wanted %q, got %q", tc.want, feed.Channel.Title)
+			}
+		})
+	}
+}
+
+func TestDeclareUTF8(t *testing.T) {
+	input := []byte(`<?xml version="1.0" encoding='ISO-8859-1' standalone="yes"?><rss/>`)
+	want := []byte(`<?xml version="1.0" encoding='UTF-8' standalone="yes"?><rss/>`)
+	if got := declareUTF8(input); !bytes.Equal(got, want) {
+		t.Errorf("wanted %q, got %q", want, got)
+	}
+}
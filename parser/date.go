@@ -0,0 +1,304 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidDate is returned by ParseDate when a value does not match any of
+// the layouts we know about, even after cleaning it up.
+var ErrInvalidDate = errors.New("invalid date")
+
+// ErrUnknownZone is returned by ParseDate, along with the time read as if it
+// were UTC, when a value names a time zone whose offset we do not know.
+var ErrUnknownZone = errors.New("unknown time zone")
+
+// rfc822Layouts are tried, in order, against dates that look like RFC 822 or
+// RFC 1123 dates once the weekday has been stripped and month names and zones
+// have been normalised by cleanRFC822. Named zones are always replaced by
+// their offsets, so there are no layouts for them.
+var rfc822Layouts = []string{
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+}
+
+// isoLayouts are tried against dates that start with a four digit year, which
+// covers RFC 3339, the W3C profile of ISO 8601 used by Dublin Core, and the
+// SQL-ish timestamps some generators emit.
+var isoLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05 -0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// zoneOffsets maps the timezone abbreviations RFC 822 allows, plus the most
+// common ones seen in the wild, to numeric offsets. Go only knows the offset
+// of an abbreviation if it matches the local zone, so we resolve them
+// ourselves rather than silently treating them all as UTC.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"SGT":  "+0800",
+	"HKT":  "+0800",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+	"AST":  "-0400",
+	"ADT":  "-0300",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+}
+
+// rfc822Zones are the zone names RFC 822 itself allows, as opposed to the
+// other abbreviations in zoneOffsets that feeds use anyway.
+var rfc822Zones = map[string]bool{
+	"UT":  true,
+	"GMT": true,
+	"Z":   true,
+	"EST": true,
+	"EDT": true,
+	"CST": true,
+	"CDT": true,
+	"MST": true,
+	"MDT": true,
+	"PST": true,
+	"PDT": true,
+}
+
+// monthNames maps full and irregular month names to the three letter
+// abbreviations used in the layouts above.
+var monthNames = map[string]string{
+	"january":   "Jan",
+	"february":  "Feb",
+	"march":     "Mar",
+	"april":     "Apr",
+	"june":      "Jun",
+	"july":      "Jul",
+	"august":    "Aug",
+	"sept":      "Sep",
+	"september": "Sep",
+	"october":   "Oct",
+	"november":  "Nov",
+	"december":  "Dec",
+}
+
+var (
+	reWeekday      = regexp.MustCompile(`^(?i)(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?,?\s*`)
+	reRFC822Day    = regexp.MustCompile(`^(Mon|Tue|Wed|Thu|Fri|Sat|Sun), ?$`)
+	reParenthetic  = regexp.MustCompile(`\s*\([^)]*\)\s*$`)
+	reWord         = regexp.MustCompile(`[A-Za-z]+\.?`)
+	reZoneWithGMT  = regexp.MustCompile(`\b(?:GMT|UTC?)\s*([+-]\d)`)
+	reColonOffset  = regexp.MustCompile(`([+-]\d\d):?(\d\d)$`)
+	reShortOffset  = regexp.MustCompile(`([+-])(\d)(\d\d)$`)
+	reHourOffset   = regexp.MustCompile(`\s([+-]\d\d)$`)
+	reISOPrefix    = regexp.MustCompile(`^\d{4}(-\d\d)?(-\d\d)?([Tt ]|$)`)
+	reSpaces       = regexp.MustCompile(`\s+`)
+	reCommaYear    = regexp.MustCompile(`(\d),\s`)
+	reTrailingZone = regexp.MustCompile(`\s([A-Za-z]{1,5})$`)
+	reCtimeZone    = regexp.MustCompile(`\s([A-Za-z]{1,5})\s\d{4}$`)
+)
+
+// ParseDate parses a publication date as found in a feed. It accepts RFC 822
+// and RFC 1123 dates with or without a weekday, with numeric or named zones and
+// single digit days, as well as RFC 3339 and the common ISO 8601 variants.
+//
+// On success the layout of value as published is returned alongside the time,
+// so that callers can report on feeds that publish irregular dates. It is
+// empty if value only parsed once it had been cleaned up. A value naming a
+// zone we do not know the offset of is read as UTC and returned with
+// ErrUnknownZone.
+func ParseDate(value string) (time.Time, string, error) {
+	s := reSpaces.ReplaceAllString(strings.TrimSpace(value), " ")
+	if s == "" {
+		return time.Time{}, "", errors.Wrap(ErrInvalidDate, "empty date")
+	}
+
+	var c cleaned
+	layouts := rfc822Layouts
+	if reISOPrefix.MatchString(s) {
+		c = cleanISO(s)
+		layouts = isoLayouts
+	} else {
+		c = cleanRFC822(s)
+	}
+
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, c.value)
+		if err != nil {
+			continue
+		}
+		if c.unknownZone != "" {
+			return t, "", ErrUnknownZone
+		}
+		return t, c.publishedLayout(layout), nil
+	}
+	return time.Time{}, "", errors.Wrapf(ErrInvalidDate, "%q", value)
+}
+
+// cleaned is a date rewritten into a form our layouts can parse, along with
+// what had to be done to it.
+type cleaned struct {
+	value string
+
+	// weekday is set if the date began with an abbreviated weekday, and
+	// namedZone if it named one of the zones RFC 822 allows. Both are
+	// replaced in value but allowed by the specification.
+	weekday   bool
+	namedZone bool
+
+	// irregular is set if the date had to be rewritten in any other way.
+	irregular bool
+
+	// unknownZone is a zone abbreviation whose offset we do not know,
+	// which has been replaced by UTC.
+	unknownZone string
+}
+
+// publishedLayout returns the layout of the date as published, given the
+// layout that matched its cleaned value, or an empty string if it was
+// irregular.
+func (c cleaned) publishedLayout(layout string) string {
+	if c.irregular || c.unknownZone != "" {
+		return ""
+	}
+	if c.namedZone {
+		layout = strings.Replace(layout, "-0700", "MST", 1)
+	}
+	if c.weekday {
+		layout = "Mon, " + layout
+	}
+	return layout
+}
+
+// rewrite replaces every match of re in the value with repl, noting that the
+// date was irregular if that changed anything.
+func (c *cleaned) rewrite(re *regexp.Regexp, repl string) {
+	if s := re.ReplaceAllString(c.value, repl); s != c.value {
+		c.value = s
+		c.irregular = true
+	}
+}
+
+// resolveZone replaces the zone abbreviation captured by re with its numeric
+// offset. Go only knows the offset of an abbreviation if it matches the local
+// zone, so we resolve them ourselves rather than silently treating them all as
+// UTC.
+func (c *cleaned) resolveZone(re *regexp.Regexp) {
+	m := re.FindStringSubmatchIndex(c.value)
+	if m == nil {
+		return
+	}
+	zone := c.value[m[2]:m[3]]
+	if _, err := time.Parse("Jan", zone); err == nil {
+		return
+	}
+	offset, ok := zoneOffsets[strings.ToUpper(zone)]
+	switch {
+	case !ok:
+		c.unknownZone = zone
+		offset = "+0000"
+	case rfc822Zones[zone]:
+		c.namedZone = true
+	default:
+		c.irregular = true
+	}
+	c.value = c.value[:m[2]] + offset + c.value[m[3]:]
+}
+
+func cleanISO(s string) cleaned {
+	c := cleaned{value: s}
+	c.resolveZone(reTrailingZone)
+	if c.namedZone {
+		// ISO 8601 has no named zones at all.
+		c.namedZone = false
+		c.irregular = true
+	}
+	if strings.HasSuffix(c.value, "z") {
+		c.value = c.value[:len(c.value)-1] + "Z"
+		c.irregular = true
+	}
+	if t := strings.Replace(c.value, "t", "T", 1); t != c.value {
+		c.value = t
+		c.irregular = true
+	}
+	return c
+}
+
+func cleanRFC822(s string) cleaned {
+	c := cleaned{value: s}
+	if day := reWeekday.FindString(c.value); day != "" {
+		c.value = c.value[len(day):]
+		c.weekday = reRFC822Day.MatchString(day)
+		c.irregular = !c.weekday
+	}
+	c.rewrite(reParenthetic, "")
+	c.rewrite(reCommaYear, "$1 ")
+	c.rewrite(reZoneWithGMT, "$1")
+	months := reWord.ReplaceAllStringFunc(c.value, func(word string) string {
+		word = strings.TrimSuffix(word, ".")
+		if abbr, ok := monthNames[strings.ToLower(word)]; ok {
+			return abbr
+		}
+		return word
+	})
+	if months != c.value {
+		c.value = months
+		c.irregular = true
+	}
+	if reCtimeZone.MatchString(c.value) {
+		c.resolveZone(reCtimeZone)
+	} else {
+		c.resolveZone(reTrailingZone)
+	}
+	c.rewrite(reHourOffset, " ${1}00")
+	c.rewrite(reColonOffset, "$1$2")
+	c.rewrite(reShortOffset, "${1}0$2$3")
+	return c
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+
+	testcases := []struct {
+		name   string
+		input  string
+		want   time.Time
+		layout string
+		zone   bool
+		err    bool
+	}{
+		{name: "rfc1123", input: "Mon, 08 Apr 2019 18:36:48 GMT", want: utc(2019, 4, 8, 18, 36, 48), layout: "Mon, 2 Jan 2006 15:04:05 MST"},
+		{name: "rfc1123z single digit day", input: "Mon, 8 Apr 2019 17:45:52 +0000", want: utc(2019, 4, 8, 17, 45, 52), layout: "Mon, 2 Jan 2006 15:04:05 -0700"},
+		{name: "no weekday", input: "8 Apr 2019 17:45:52 +0000", want: utc(2019, 4, 8, 17, 45, 52), layout: "2 Jan 2006 15:04:05 -0700"},
+		{name: "long weekday", input: "Monday, 8 April 2019 17:45:52 GMT", want: utc(2019, 4, 8, 17, 45, 52), layout: ""},
+		{name: "wrong weekday", input: "Tue, 08 Apr 2019 18:36:48 GMT", want: utc(2019, 4, 8, 18, 36, 48), layout: "Mon, 2 Jan 2006 15:04:05 MST"},
+		{name: "no seconds", input: "Mon, 08 Apr 2019 18:36 +0000", want: utc(2019, 4, 8, 18, 36, 0), layout: "Mon, 2 Jan 2006 15:04 -0700"},
+		{name: "two digit year", input: "Mon, 08 Apr 19 18:36:48 +0000", want: utc(2019, 4, 8, 18, 36, 48), layout: "Mon, 2 Jan 06 15:04:05 -0700"},
+		{name: "named zone", input: "Mon, 08 Apr 2019 13:36:48 EST", want: utc(2019, 4, 8, 18, 36, 48), layout: "Mon, 2 Jan 2006 15:04:05 MST"},
+		{name: "daylight zone", input: "Mon, 08 Apr 2019 11:36:48 PDT", want: utc(2019, 4, 8, 18, 36, 48), layout: "Mon, 2 Jan 2006 15:04:05 MST"},
+		{name: "colon in offset", input: "Mon, 08 Apr 2019 20:36:48 +02:00", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "gmt with offset", input: "Mon, 08 Apr 2019 20:36:48 GMT+0200", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "parenthesised zone", input: "Mon, 08 Apr 2019 18:36:48 +0000 (UTC)", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "sept", input: "Tues, 10 Sept 2019 18:36:48 GMT", want: utc(2019, 9, 10, 18, 36, 48), layout: ""},
+		{name: "extra whitespace", input: "  Mon,  08 Apr 2019\n18:36:48   GMT ", want: utc(2019, 4, 8, 18, 36, 48), layout: "Mon, 2 Jan 2006 15:04:05 MST"},
+		{name: "date only", input: "08 Apr 2019", want: utc(2019, 4, 8, 0, 0, 0), layout: "2 Jan 2006"},
+		{name: "rfc3339", input: "2019-04-08T18:36:48Z", want: utc(2019, 4, 8, 18, 36, 48), layout: time.RFC3339Nano},
+		{name: "rfc3339 offset", input: "2019-04-08T20:36:48+02:00", want: utc(2019, 4, 8, 18, 36, 48), layout: time.RFC3339Nano},
+		{name: "rfc3339 fraction", input: "2019-04-08T18:36:48.000Z", want: utc(2019, 4, 8, 18, 36, 48), layout: time.RFC3339Nano},
+		{name: "iso without zone", input: "2019-04-08T18:36:48", want: utc(2019, 4, 8, 18, 36, 48), layout: "2006-01-02T15:04:05.999999999"},
+		{name: "iso with space", input: "2019-04-08 18:36:48", want: utc(2019, 4, 8, 18, 36, 48), layout: "2006-01-02 15:04:05.999999999"},
+		{name: "iso date", input: "2019-04-08", want: utc(2019, 4, 8, 0, 0, 0), layout: "2006-01-02"},
+		{name: "other named zone", input: "Mon, 08 Apr 2019 21:36:48 EEST", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "ctime", input: "Mon Apr 8 13:36:48 EST 2019", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "iso named zone", input: "2019-04-08 20:36:48 CEST", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "iso utc", input: "2019-04-08T18:36:48 UTC", want: utc(2019, 4, 8, 18, 36, 48), layout: ""},
+		{name: "unknown zone", input: "Mon, 08 Apr 2019 18:36:48 XYZT", want: utc(2019, 4, 8, 18, 36, 48), zone: true},
+		{name: "unknown ctime zone", input: "Mon Apr 8 18:36:48 XYZT 2019", want: utc(2019, 4, 8, 18, 36, 48), zone: true},
+		{name: "empty", input: "", err: true},
+		{name: "garbage", input: "yesterday", err: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, layout, err := ParseDate(tc.input)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, but got none")
+				}
+				return
+			}
+			if tc.zone {
+				if err != ErrUnknownZone {
+					t.Fatalf("expected error %v, got %v", ErrUnknownZone, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if layout != tc.layout {
+				t.Errorf("expected layout %q, got %q", tc.layout, layout)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("wanted %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
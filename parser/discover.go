@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/haleyrc/rss/markup"
+)
+
+// Candidate is a feed found by Discover.
+type Candidate struct {
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Format Format `json:"format"`
+}
+
+// feedTypes maps the media types used in <link rel="alternate"> elements to
+// the formats they advertise.
+var feedTypes = map[string]Format{
+	"application/rss+xml":   FormatRSS,
+	"application/atom+xml":  FormatAtom,
+	"application/rdf+xml":   FormatRDF,
+	"application/feed+json": FormatJSON,
+	"application/json":      FormatJSON,
+}
+
+// commonPaths are tried, relative to the root of the site, when a page does
+// not link to any feeds. They cover the defaults of the popular blog engines
+// and static site generators.
+var commonPaths = []string{
+	"/feed",
+	"/rss.xml",
+	"/atom.xml",
+	"/index.xml",
+}
+
+// Discover finds the feeds published by the site at pageURL. If pageURL is
+// itself a feed, that is the only candidate. Otherwise the page is searched for
+// <link rel="alternate"> elements advertising feeds, in the order they appear,
+// and if there are none the common feed locations of the site are probed. An
+// empty slice is returned if nothing is found.
+func (f *Fetcher) Discover(ctx context.Context, pageURL string) ([]Candidate, error) {
+	_, doc, err := f.get(ctx, pageURL, Validators{})
+	if err != nil {
+		return nil, err
+	}
+
+	if feed, err := load(doc.body, doc.contentType); err == nil {
+		return []Candidate{{URL: doc.url, Title: feed.Channel.Title, Format: feed.Format}}, nil
+	}
+	return f.discover(ctx, doc)
+}
+
+// DiscoverPage finds the feeds published by the site of a web page that Fetch
+// refused, as Discover does, without downloading the page again.
+func (f *Fetcher) DiscoverPage(ctx context.Context, page *HTMLPageError) ([]Candidate, error) {
+	return f.discover(ctx, page.doc)
+}
+
+// discover searches the web page doc for <link> elements advertising feeds,
+// and probes the common feed locations of its site if there are none.
+func (f *Fetcher) discover(ctx context.Context, doc document) ([]Candidate, error) {
+	if !isHTML(doc.body, doc.contentType) {
+		return []Candidate{}, nil
+	}
+
+	b, err := toUTF8(doc.body, doc.contentType)
+	if err != nil {
+		b = doc.body
+	}
+	if candidates := linkedFeeds(string(b), doc.url); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	candidates := []Candidate{}
+	for _, path := range commonPaths {
+		u := ResolveURL(doc.url, path)
+		result, err := f.Fetch(ctx, u, Validators{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			URL:    u,
+			Title:  result.Feed.Channel.Title,
+			Format: result.Feed.Format,
+		})
+	}
+	return candidates, nil
+}
+
+// linkedFeeds returns the feeds advertised by the <link> elements of an HTML
+// page. Relative URLs are resolved against the page's <base>, if it has one,
+// or pageURL. Links without a title are given the title of the page.
+func linkedFeeds(page, pageURL string) []Candidate {
+	var (
+		candidates []Candidate
+		pageTitle  string
+		inTitle    bool
+		base       = pageURL
+		seen       = make(map[string]bool)
+	)
+	for _, tok := range markup.Tokenize(page) {
+		switch tok.Type {
+		case markup.TextToken:
+			if inTitle && pageTitle == "" {
+				pageTitle = strings.TrimSpace(tok.Data)
+			}
+		case markup.EndTagToken:
+			if tok.Data == "title" {
+				inTitle = false
+			}
+		case markup.StartTagToken, markup.SelfClosingTagToken:
+			switch tok.Data {
+			case "title":
+				inTitle = tok.Type == markup.StartTagToken
+			case "base":
+				if href, ok := tok.Get("href"); ok {
+					base = ResolveURL(pageURL, strings.TrimSpace(href))
+				}
+			case "link":
+				c, ok := linkCandidate(tok, base)
+				if !ok || seen[c.URL] {
+					continue
+				}
+				seen[c.URL] = true
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	for i := range candidates {
+		if candidates[i].Title == "" {
+			candidates[i].Title = pageTitle
+		}
+	}
+	return candidates
+}
+
+// linkCandidate returns the feed advertised by a <link> element, if it is
+// one.
+func linkCandidate(tok markup.Token, base string) (Candidate, bool) {
+	rel, _ := tok.Get("rel")
+	if !hasToken(rel, "alternate") || hasToken(rel, "stylesheet") {
+		return Candidate{}, false
+	}
+	typ, _ := tok.Get("type")
+	mediaType, _, err := mime.ParseMediaType(typ)
+	if err != nil {
+		return Candidate{}, false
+	}
+	format, ok := feedTypes[mediaType]
+	if !ok {
+		return Candidate{}, false
+	}
+	href, _ := tok.Get("href")
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return Candidate{}, false
+	}
+	title, _ := tok.Get("title")
+	return Candidate{
+		URL:    ResolveURL(base, href),
+		Title:  strings.TrimSpace(title),
+		Format: format,
+	}, true
+}
+
+// hasToken reports whether the space separated list s contains token,
+// ignoring case.
+func hasToken(s, token string) bool {
+	for _, field := range strings.Fields(s) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHTML reports whether a document is a web page, going by its Content-Type
+// or, failing that, by sniffing its content. Feeds served with the wrong
+// Content-Type are not web pages.
+func isHTML(b []byte, contentType string) bool {
+	if _, err := DetectFormat(b); err == nil {
+		return false
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			return true
+		}
+	}
+	return strings.HasPrefix(http.DetectContentType(b), "text/html")
+}
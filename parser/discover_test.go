@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	atom, err := ioutil.ReadFile(filepath.Join("..", "testdata", "atom.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/linked", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Example Blog</title>
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.rss">
+	<link rel="Alternate" type="application/atom+xml" href="comments.atom">
+	<link rel="alternate" type="application/feed+json; charset=utf-8" href="https://cdn.example.org/feed.json">
+	<link rel="alternate" hreflang="fr" href="/fr/">
+</head>
+<body></body>
+</html>`))
+	})
+	mux.HandleFunc("/unlinked", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Quiet Blog</title></head><body></body></html>`))
+	})
+	mux.HandleFunc("/atom.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write(atom)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	testcases := []struct {
+		name string
+		url  string
+		want []Candidate
+	}{
+		{
+			name: "linked",
+			url:  srv.URL + "/linked",
+			want: []Candidate{
+				{URL: srv.URL + "/posts.rss", Title: "Posts", Format: FormatRSS},
+				{URL: srv.URL + "/comments.atom", Title: "Example Blog", Format: FormatAtom},
+				{URL: "https://cdn.example.org/feed.json", Title: "Example Blog", Format: FormatJSON},
+			},
+		},
+		{
+			name: "common paths",
+			url:  srv.URL + "/unlinked",
+			want: []Candidate{
+				{URL: srv.URL + "/atom.xml", Title: "Example Atom Feed", Format: FormatAtom},
+			},
+		},
+		{
+			name: "feed",
+			url:  srv.URL + "/atom.xml",
+			want: []Candidate{
+				{URL: srv.URL + "/atom.xml", Title: "Example Atom Feed", Format: FormatAtom},
+			},
+		},
+	}
+
+	fetcher := NewFetcher(FetcherConfig{})
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := fetcher.Discover(context.Background(), tc.url)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %d candidates, got %d: %+v", len(tc.want), len(got), got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expected candidate %d to be %+v, got %+v", i, tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchHTMLPage(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Home</title>` +
+			`<link rel="alternate" type="application/rss+xml" href="/feed.rss"></head><body><p>Hello & welcome</body></html>`))
+	}))
+	defer srv.Close()
+
+	fetcher := NewFetcher(FetcherConfig{})
+	_, err := fetcher.Fetch(context.Background(), srv.URL, Validators{})
+	page, ok := err.(*HTMLPageError)
+	if !ok {
+		t.Fatalf("expected an HTML page error, got %v", err)
+	}
+	if page.URL != srv.URL {
+		t.Errorf("expected url %q, got %q", srv.URL, page.URL)
+	}
+
+	candidates, err := fetcher.DiscoverPage(context.Background(), page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Candidate{URL: srv.URL + "/feed.rss", Title: "Home", Format: FormatRSS}
+	if len(candidates) != 1 || candidates[0] != want {
+		t.Errorf("expected candidates %+v, got %+v", []Candidate{want}, candidates)
+	}
+	if requests != 1 {
+		t.Errorf("expected the page to be requested %d time, got %d", 1, requests)
+	}
+}
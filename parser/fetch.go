@@ -0,0 +1,447 @@
+package parser
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+// Validators are the values a server sent with a feed that let us ask whether
+// it has changed since we last fetched it.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// FetchResult is the outcome of a successful fetch. When NotModified is true
+// the server confirmed our copy is current and Feed is empty.
+type FetchResult struct {
+	Feed        Feed
+	NotModified bool
+	Validators  Validators
+
+	// URL is the address the feed should be fetched from in future. It
+	// differs from the requested URL only when the server answered with a
+	// permanent redirect; temporary redirects are followed but not
+	// remembered.
+	URL string
+
+	// NextFetch is the earliest time the server would like us to fetch the
+	// feed again, based on Cache-Control, Expires or Retry-After. It is zero
+	// if the server expressed no preference.
+	NextFetch time.Time
+}
+
+// StatusError is returned when the server responds with anything other than
+// 200 or 304.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Time
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server responded with status %d", e.StatusCode)
+}
+
+// Defaults used by NewFetcher for any FetcherConfig fields left at their zero
+// value.
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultUserAgent    = "haleyrc-rss/1.0 (+https://github.com/haleyrc/rss)"
+	DefaultMaxBodyBytes = 10 << 20
+)
+
+// ErrBodyTooLarge is returned when a feed, once decompressed, is larger than
+// the fetcher's MaxBodyBytes.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// HTMLPageError is returned by Fetch when the URL points at a web page rather
+// than a feed. It holds on to the page, so that DiscoverPage can find the feeds
+// it links to without downloading it again.
+type HTMLPageError struct {
+	// URL is the address of the page, after any redirects.
+	URL string
+
+	doc document
+}
+
+func (e *HTMLPageError) Error() string {
+	return "document is a web page, not a feed"
+}
+
+// FetcherConfig controls how a Fetcher talks to publishers.
+type FetcherConfig struct {
+	// Timeout bounds the whole request, from connecting until the body has
+	// been read.
+	Timeout time.Duration
+
+	// UserAgent is sent with every request. Publishers use it to tell feed
+	// readers apart, and some refuse requests without one.
+	UserAgent string
+
+	// Proxy, if set, is used for all requests. Otherwise the proxy is taken
+	// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy *url.URL
+
+	// MaxBodyBytes limits the size of a decompressed feed so that a broken
+	// or hostile server can not exhaust our memory.
+	MaxBodyBytes int64
+}
+
+// Fetcher downloads feeds over HTTP, using conditional requests so that
+// unchanged feeds cost the publisher a 304 rather than the whole document.
+// A Fetcher is safe for concurrent use and should be shared, so that
+// connections to publishers are reused.
+type Fetcher struct {
+	client       *http.Client
+	userAgent    string
+	maxBodyBytes int64
+}
+
+func NewFetcher(config FetcherConfig) *Fetcher {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if config.Proxy != nil {
+		transport.Proxy = http.ProxyURL(config.Proxy)
+	}
+	// We ask for and decode compressed responses ourselves, since the
+	// transport only knows about gzip.
+	transport.DisableCompression = true
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+		userAgent:    config.UserAgent,
+		maxBodyBytes: config.MaxBodyBytes,
+	}
+}
+
+// Fetch downloads and decodes the feed at url. The validators from a previous
+// fetch, if any, are sent so that the server can reply that nothing changed.
+// When the server rejects the request with a status error, the returned result
+// still carries any Retry-After time so that callers can back off. The request
+// is abandoned if ctx is cancelled.
+func (f *Fetcher) Fetch(ctx context.Context, url string, v Validators) (FetchResult, error) {
+	result, doc, err := f.get(ctx, url, v)
+	if err != nil || result.NotModified {
+		return result, err
+	}
+
+	feed, err := doc.load()
+	if err != nil {
+		if isHTML(doc.body, doc.contentType) {
+			return FetchResult{}, &HTMLPageError{URL: doc.url, doc: doc}
+		}
+		return FetchResult{}, err
+	}
+	result.Feed = feed
+
+	return result, nil
+}
+
+// LoadDocument decodes a feed that was delivered to us rather than fetched,
+// such as content pushed by a WebSub hub. url is the address the feed is
+// published at, and header holds the headers it was delivered with. Relative
+// links are resolved and Link headers are read as they are by Fetch.
+func LoadDocument(url string, header http.Header, body []byte) (Feed, error) {
+	doc := document{
+		url:         url,
+		contentType: header.Get("Content-Type"),
+		links:       parseLinkHeader(header["Link"]),
+		body:        body,
+	}
+	return doc.load()
+}
+
+// document is a response body along with the headers needed to interpret it.
+type document struct {
+	url         string
+	contentType string
+	links       []Link
+	body        []byte
+}
+
+// load decodes the document, resolving the channel's base URL and links
+// against the address it came from.
+func (doc document) load() (Feed, error) {
+	feed, err := load(doc.body, doc.contentType)
+	if err != nil {
+		return Feed{}, err
+	}
+	c := &feed.Channel
+	c.Base = inheritBase(doc.url, c.Base)
+	for i, l := range c.Links {
+		c.Links[i].Href = ResolveURL(c.Base, l.Href)
+	}
+	// Links sent as HTTP headers take precedence over those in the
+	// document, as WebSub publishers are told to rely on them.
+	for i := len(doc.links) - 1; i >= 0; i-- {
+		l := doc.links[i]
+		l.Href = ResolveURL(doc.url, l.Href)
+		c.Links = append([]Link{l}, c.Links...)
+	}
+	return feed, nil
+}
+
+// get performs a conditional GET of url and reads the body, without trying to
+// decode it. The body is only read for a 200 response.
+func (f *Fetcher) get(ctx context.Context, url string, v Validators) (FetchResult, document, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return FetchResult{}, document{}, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return FetchResult{}, document{}, err
+	}
+	defer resp.Body.Close()
+
+	now := time.Now()
+	result := FetchResult{
+		Validators: Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+		NextFetch: nextFetch(resp.Header, now),
+		URL:       permanentURL(resp),
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		// A 304 need not repeat the validators, in which case the ones
+		// we sent are still good.
+		if result.Validators.ETag == "" {
+			result.Validators.ETag = v.ETag
+		}
+		if result.Validators.LastModified == "" {
+			result.Validators.LastModified = v.LastModified
+		}
+		result.NotModified = true
+		return result, document{}, nil
+	default:
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+		if retryAfter.After(result.NextFetch) {
+			result.NextFetch = retryAfter
+		}
+		return result, document{}, &StatusError{StatusCode: resp.StatusCode, RetryAfter: retryAfter}
+	}
+
+	b, err := f.readBody(resp)
+	if err != nil {
+		return FetchResult{}, document{}, err
+	}
+
+	doc := document{
+		url:         resp.Request.URL.String(),
+		contentType: resp.Header.Get("Content-Type"),
+		links:       parseLinkHeader(resp.Header["Link"]),
+		body:        b,
+	}
+	return result, doc, nil
+}
+
+// permanentURL returns the URL reached by following only the permanent
+// redirects at the start of the chain that led to resp. Once a temporary
+// redirect is seen the URL before it is still the canonical one, whatever
+// follows.
+func permanentURL(resp *http.Response) string {
+	var chain []*http.Request
+	for req := resp.Request; ; req = req.Response.Request {
+		chain = append(chain, req)
+		if req.Response == nil {
+			break
+		}
+	}
+
+	// The chain runs from the final request back to the original one.
+	url := chain[len(chain)-1].URL.String()
+	for i := len(chain) - 2; i >= 0; i-- {
+		switch chain[i].Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			url = chain[i].URL.String()
+		default:
+			return url
+		}
+	}
+	return url
+}
+
+// readBody decompresses the response body according to its Content-Encoding
+// and reads at most maxBodyBytes of it.
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, error) {
+	body, err := decodeBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, errors.Wrap(err, "decompress body")
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(body, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > f.maxBodyBytes {
+		return nil, ErrBodyTooLarge
+	}
+	return b, nil
+}
+
+// decodeBody wraps r in a reader that undoes the given content encoding.
+func decodeBody(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return ioutil.NopCloser(r), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// HTTP deflate is meant to be zlib wrapped, but enough servers
+		// send a bare deflate stream that we accept both.
+		br := bufio.NewReader(r)
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	default:
+		return nil, errors.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// isZlibHeader reports whether b starts with a zlib header using the deflate
+// compression method, as described in RFC 1950.
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
+
+// nextFetch returns the time at which the response becomes stale according to
+// its Cache-Control or Expires headers.
+func nextFetch(h http.Header, now time.Time) time.Time {
+	if cc := h.Get("Cache-Control"); cc != "" {
+		for _, directive := range strings.Split(cc, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			switch {
+			case directive == "no-cache" || directive == "no-store":
+				return time.Time{}
+			case strings.HasPrefix(directive, "max-age="):
+				seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+				if err != nil || seconds <= 0 {
+					return time.Time{}
+				}
+				age, _ := strconv.Atoi(h.Get("Age"))
+				return now.Add(time.Duration(seconds-age) * time.Second)
+			}
+		}
+	}
+	if expires := h.Get("Expires"); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil && t.After(now) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return time.Time{}
+		}
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// parseLinkHeader parses the values of RFC 8288 Link headers. A link with
+// several space separated relations is returned once for each of them.
+func parseLinkHeader(values []string) []Link {
+	var links []Link
+	for _, value := range values {
+		for value != "" {
+			start := strings.Index(value, "<")
+			end := strings.Index(value, ">")
+			if start == -1 || end < start {
+				break
+			}
+			href := value[start+1 : end]
+			value = value[end+1:]
+
+			// Parameters run until the next link, which starts after
+			// a comma outside of a quoted string.
+			next, quoted := len(value), false
+			for i, c := range value {
+				if c == '"' {
+					quoted = !quoted
+				} else if c == ',' && !quoted {
+					next = i
+					break
+				}
+			}
+			var rel, typ string
+			for _, param := range strings.Split(value[:next], ";") {
+				kv := strings.SplitN(param, "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				v := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+				switch strings.ToLower(strings.TrimSpace(kv[0])) {
+				case "rel":
+					rel = v
+				case "type":
+					typ = v
+				}
+			}
+			for _, r := range strings.Fields(rel) {
+				links = append(links, Link{Href: strings.TrimSpace(href), Rel: strings.ToLower(r), Type: typ})
+			}
+			if next == len(value) {
+				break
+			}
+			value = value[next+1:]
+		}
+	}
+	return links
+}
@@ -0,0 +1,409 @@
+package parser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestFetcherConditionalGet(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "hackernews.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const etag = `"v1"`
+	const lastModified = "Mon, 08 Apr 2019 21:40:21 GMT"
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	fetcher := NewFetcher(FetcherConfig{})
+
+	first, err := fetcher.Fetch(context.Background(), srv.URL, Validators{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.NotModified {
+		t.Errorf("expected first fetch to be modified")
+	}
+	if want := "Hacker News"; first.Feed.Channel.Title != want {
+		t.Errorf("expected title %q, got %q", want, first.Feed.Channel.Title)
+	}
+	if first.Validators.ETag != etag {
+		t.Errorf("expected etag %q, got %q", etag, first.Validators.ETag)
+	}
+	if first.Validators.LastModified != lastModified {
+		t.Errorf("expected last modified %q, got %q", lastModified, first.Validators.LastModified)
+	}
+	if d := time.Until(first.NextFetch); d < 9*time.Minute || d > 10*time.Minute {
+		t.Errorf("expected next fetch in about ten minutes, got %s", d)
+	}
+
+	second, err := fetcher.Fetch(context.Background(), srv.URL, first.Validators)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !second.NotModified {
+		t.Errorf("expected second fetch to be not modified")
+	}
+	if second.Validators != first.Validators {
+		t.Errorf("expected validators %+v to be kept, got %+v", first.Validators, second.Validators)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestFetcherRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	result, err := NewFetcher(FetcherConfig{}).Fetch(context.Background(), srv.URL, Validators{})
+	if err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+	statusErr, ok := err.(*StatusError)
+	if !ok {
+		t.Fatalf("expected a status error, got %T", err)
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, statusErr.StatusCode)
+	}
+	if d := time.Until(result.NextFetch); d < 119*time.Second || d > 120*time.Second {
+		t.Errorf("expected next fetch in two minutes, got %s", d)
+	}
+}
+
+func TestFetcherContentEncoding(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "hackernews.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name     string
+		encoding string
+		writer   func(w io.Writer) io.WriteCloser
+	}{
+		{name: "identity", encoding: "", writer: nopWriteCloser},
+		{name: "gzip", encoding: "gzip", writer: func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{name: "zlib", encoding: "deflate", writer: func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{name: "raw deflate", encoding: "deflate", writer: func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}},
+		{name: "brotli", encoding: "br", writer: func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var compressed bytes.Buffer
+			w := tc.writer(&compressed)
+			w.Write(body)
+			w.Close()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.encoding != "" {
+					w.Header().Set("Content-Encoding", tc.encoding)
+				}
+				w.Write(compressed.Bytes())
+			}))
+			defer srv.Close()
+
+			result, err := NewFetcher(FetcherConfig{}).Fetch(context.Background(), srv.URL, Validators{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := "Hacker News"; result.Feed.Channel.Title != want {
+				t.Errorf("expected title %q, got %q", want, result.Feed.Channel.Title)
+			}
+		})
+	}
+}
+
+func TestFetcherConfig(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "hackernews.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var userAgent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	fetcher := NewFetcher(FetcherConfig{UserAgent: "test-agent/1.0"})
+	if _, err := fetcher.Fetch(context.Background(), srv.URL, Validators{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if userAgent != "test-agent/1.0" {
+		t.Errorf("expected user agent %q, got %q", "test-agent/1.0", userAgent)
+	}
+
+	fetcher = NewFetcher(FetcherConfig{MaxBodyBytes: int64(len(body) - 1)})
+	if _, err := fetcher.Fetch(context.Background(), srv.URL, Validators{}); err != ErrBodyTooLarge {
+		t.Errorf("expected error %v, got %v", ErrBodyTooLarge, err)
+	}
+}
+
+func TestFetcherCancel(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := NewFetcher(FetcherConfig{}).Fetch(ctx, srv.URL, Validators{}); err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected fetch to be cancelled, took %s", d)
+	}
+
+	start = time.Now()
+	fetcher := NewFetcher(FetcherConfig{Timeout: 50 * time.Millisecond})
+	if _, err := fetcher.Fetch(context.Background(), srv.URL, Validators{}); err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected fetch to time out, took %s", d)
+	}
+}
+
+func nopWriteCloser(w io.Writer) io.WriteCloser {
+	return struct {
+		io.Writer
+		io.Closer
+	}{w, ioutil.NopCloser(nil)}
+}
+
+func TestFetcherPermanentRedirect(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "hackernews.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/permanent", http.RedirectHandler("/feed", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/feed", http.StatusFound))
+	mux.Handle("/moved-then-temporary", http.RedirectHandler("/temporary", http.StatusMovedPermanently))
+	mux.Handle("/temporary-then-moved", http.RedirectHandler("/moved", http.StatusTemporaryRedirect))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	testcases := []struct {
+		path string
+		want string
+	}{
+		{path: "/feed", want: "/feed"},
+		{path: "/moved", want: "/feed"},
+		{path: "/permanent", want: "/feed"},
+		{path: "/temporary", want: "/temporary"},
+		{path: "/moved-then-temporary", want: "/temporary"},
+		{path: "/temporary-then-moved", want: "/temporary-then-moved"},
+	}
+
+	fetcher := NewFetcher(FetcherConfig{})
+	for _, tc := range testcases {
+		t.Run(tc.path, func(t *testing.T) {
+			result, err := fetcher.Fetch(context.Background(), srv.URL+tc.path, Validators{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := srv.URL + tc.want; result.URL != want {
+				t.Errorf("expected url %q, got %q", want, result.URL)
+			}
+		})
+	}
+}
+
+func TestFetcherBase(t *testing.T) {
+	testcases := []struct {
+		name string
+		body string
+		base string
+		self string
+		item string
+	}{
+		{
+			name: "no xml:base",
+			body: `<rss version="2.0"><channel><title>T</title><atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="self" href="feed.xml"/><item><title>I</title></item></channel></rss>`,
+			base: "/blog/rss",
+			self: "/blog/feed.xml",
+		},
+		{
+			name: "relative xml:base",
+			body: `<rss version="2.0" xml:base="/site/"><channel><title>T</title><atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="self" href="feed.xml"/><item xml:base="posts/"><title>I</title></item></channel></rss>`,
+			base: "/site/",
+			self: "/site/feed.xml",
+			item: "posts/",
+		},
+		{
+			name: "atom entry xml:base",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="/site/"><title>T</title><link rel="self" href="atom.xml"/><entry xml:base="posts/"><title>I</title></entry></feed>`,
+			base: "/site/",
+			self: "/site/atom.xml",
+			item: "/site/posts/",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tc.body)
+			}))
+			defer srv.Close()
+
+			result, err := NewFetcher(FetcherConfig{}).Fetch(context.Background(), srv.URL+"/blog/rss", Validators{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := result.Feed.Channel
+			if want := srv.URL + tc.base; c.Base != want {
+				t.Errorf("expected base %q, got %q", want, c.Base)
+			}
+			if want := srv.URL + tc.self; c.SelfLink() != want {
+				t.Errorf("expected self link %q, got %q", want, c.SelfLink())
+			}
+			if len(c.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(c.Items))
+			}
+			if c.Items[0].Base != tc.item {
+				t.Errorf("expected item base %q, got %q", tc.item, c.Items[0].Base)
+			}
+		})
+	}
+}
+
+func TestNextFetch(t *testing.T) {
+	now := time.Date(2019, 4, 8, 12, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name   string
+		header http.Header
+		want   time.Time
+	}{
+		{name: "none", header: http.Header{}, want: time.Time{}},
+		{name: "max-age", header: http.Header{"Cache-Control": {"max-age=300"}}, want: now.Add(5 * time.Minute)},
+		{name: "max-age minus age", header: http.Header{"Cache-Control": {"max-age=300"}, "Age": {"60"}}, want: now.Add(4 * time.Minute)},
+		{name: "no-cache", header: http.Header{"Cache-Control": {"no-cache, max-age=300"}}, want: time.Time{}},
+		{name: "expires", header: http.Header{"Expires": {"Mon, 08 Apr 2019 13:00:00 GMT"}}, want: now.Add(time.Hour)},
+		{name: "expired", header: http.Header{"Expires": {"Mon, 08 Apr 2019 11:00:00 GMT"}}, want: time.Time{}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := nextFetch(tc.header, now); !got.Equal(tc.want) {
+				t.Errorf("wanted %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	testcases := []struct {
+		name   string
+		values []string
+		want   []Link
+	}{
+		{
+			name:   "single",
+			values: []string{`<https://hub.example.com/>; rel="hub"`},
+			want:   []Link{{Href: "https://hub.example.com/", Rel: "hub"}},
+		},
+		{
+			name:   "several in one header",
+			values: []string{`<https://hub.example.com/>; rel=hub, <https://example.com/feed.xml>; rel="self"; type="application/rss+xml"`},
+			want: []Link{
+				{Href: "https://hub.example.com/", Rel: "hub"},
+				{Href: "https://example.com/feed.xml", Rel: "self", Type: "application/rss+xml"},
+			},
+		},
+		{
+			name:   "comma in url and quoted parameter",
+			values: []string{`<https://example.com/a,b>; title="one, two"; rel="self"`, `</hub>; rel="hub"`},
+			want: []Link{
+				{Href: "https://example.com/a,b", Rel: "self"},
+				{Href: "/hub", Rel: "hub"},
+			},
+		},
+		{
+			name:   "several relations",
+			values: []string{`<https://example.com/feed.xml>; rel="self alternate"`},
+			want: []Link{
+				{Href: "https://example.com/feed.xml", Rel: "self"},
+				{Href: "https://example.com/feed.xml", Rel: "alternate"},
+			},
+		},
+		{
+			name:   "malformed",
+			values: []string{`https://hub.example.com/; rel="hub"`},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseLinkHeader(tc.values)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected links %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFetcherHubLinks(t *testing.T) {
+	const body = `<rss version="2.0"><channel><title>T</title><atom:link xmlns:atom="http://www.w3.org/2005/Atom" rel="hub" href="https://hub.example.com/"/></channel></rss>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</websub/hub>; rel="hub"`)
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	result, err := NewFetcher(FetcherConfig{}).Fetch(context.Background(), srv.URL+"/feed", Validators{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{srv.URL + "/websub/hub", "https://hub.example.com/"}
+	if got := result.Feed.Channel.HubLinks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected hubs %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+
+	"github.com/pkg/errors"
+)
+
+// Format identifies the syndication format a feed document was published in.
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatRSS     Format = "rss"
+	FormatAtom    Format = "atom"
+	FormatRDF     Format = "rdf"
+	FormatJSON    Format = "json"
+)
+
+// ErrUnknownFormat is returned when a document is well-formed but its root
+// element does not belong to any feed format we understand.
+var ErrUnknownFormat = errors.New("unknown feed format")
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// formatFromContentType returns the format implied by an HTTP Content-Type
+// header. XML media types are shared by all of the XML based formats so only
+// JSON Feed can be identified this way; everything else returns FormatUnknown
+// and must be sniffed from the body.
+func formatFromContentType(contentType string) Format {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return FormatUnknown
+	}
+	switch mediaType {
+	case "application/feed+json", "application/json":
+		return FormatJSON
+	default:
+		return FormatUnknown
+	}
+}
+
+// DetectFormat sniffs the root element of b to determine which feed format it
+// contains. Only as much of the document as is needed to find the root element
+// is decoded. Documents starting with an opening brace are assumed to be JSON
+// Feeds.
+func DetectFormat(b []byte) (Format, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(b, utf8BOM), " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSON, nil
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return FormatUnknown, ErrUnknownFormat
+		}
+		if err != nil {
+			return FormatUnknown, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "rss":
+			return FormatRSS, nil
+		case "feed":
+			return FormatAtom, nil
+		case "RDF":
+			return FormatRDF, nil
+		default:
+			return FormatUnknown, errors.Wrapf(ErrUnknownFormat, "unexpected root element <%s>", start.Name.Local)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"strings"
+)
+
+// jsonFeed is the wire representation of a JSON Feed document. Both version 1
+// and version 1.1 are supported; the only difference we care about is that 1.1
+// replaced the single author object with an authors array.
+type jsonFeed struct {
+	Version     string       `json:"version"`
+	Title       string       `json:"title"`
+	HomePageURL string       `json:"home_page_url"`
+	FeedURL     string       `json:"feed_url"`
+	Description string       `json:"description"`
+	Icon        string       `json:"icon"`
+	Favicon     string       `json:"favicon"`
+	Author      *jsonAuthor  `json:"author"`
+	Authors     []jsonAuthor `json:"authors"`
+	Items       []jsonItem   `json:"items"`
+	Hubs        []jsonHub    `json:"hubs"`
+}
+
+type jsonHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type jsonItem struct {
+	ID            json.RawMessage  `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *jsonAuthor      `json:"author"`
+	Authors       []jsonAuthor     `json:"authors"`
+	Attachments   []jsonAttachment `json:"attachments"`
+	Tags          []string         `json:"tags"`
+}
+
+type jsonAttachment struct {
+	URL               string          `json:"url"`
+	MimeType          string          `json:"mime_type"`
+	SizeInBytes       json.RawMessage `json:"size_in_bytes"`
+	DurationInSeconds json.RawMessage `json:"duration_in_seconds"`
+}
+
+type jsonAuthor struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Avatar string `json:"avatar"`
+}
+
+func loadJSON(b []byte) (Feed, error) {
+	var jf jsonFeed
+	if err := json.Unmarshal(bytes.TrimPrefix(b, utf8BOM), &jf); err != nil {
+		return Feed{}, err
+	}
+	return jf.toFeed(), nil
+}
+
+func (jf jsonFeed) toFeed() Feed {
+	c := Channel{
+		Title:       jf.Title,
+		Description: jf.Description,
+		Link:        jf.HomePageURL,
+		Image:       firstNonEmpty(jf.Icon, jf.Favicon),
+	}
+	if jf.FeedURL != "" {
+		c.Links = append(c.Links, Link{Href: jf.FeedURL, Rel: "self", Type: "application/feed+json"})
+	}
+	for _, h := range jf.Hubs {
+		if strings.EqualFold(h.Type, "WebSub") && h.URL != "" {
+			c.Links = append(c.Links, Link{Href: h.URL, Rel: "hub"})
+		}
+	}
+	c.Authors = jsonAuthors(jf.Authors, jf.Author)
+	for _, ji := range jf.Items {
+		item := Item{
+			GUID:            GUID{Value: ji.id(), IsPermaLink: "false"},
+			Title:           ji.Title,
+			Link:            firstNonEmpty(ji.URL, ji.ExternalURL),
+			PublicationDate: firstNonEmpty(ji.DatePublished, ji.DateModified),
+			Description:     ji.Summary,
+			Content:         firstNonEmpty(ji.ContentHTML, html.EscapeString(ji.ContentText)),
+		}
+		item.Authors = jsonAuthors(ji.Authors, ji.Author)
+		if len(item.Authors) == 0 {
+			item.Authors = c.Authors
+		}
+		item.Author = firstName(item.Authors)
+		for _, tag := range ji.Tags {
+			item.Categories = append(item.Categories, Category{Name: tag})
+		}
+		for _, a := range ji.Attachments {
+			item.Enclosures = append(item.Enclosures, Enclosure{
+				URL:    a.URL,
+				Type:   a.MimeType,
+				Length: jsonNumber(a.SizeInBytes),
+			})
+			if item.Duration == "" {
+				item.Duration = jsonNumber(a.DurationInSeconds)
+			}
+		}
+		c.Items = append(c.Items, item)
+	}
+	return Feed{Format: FormatJSON, Channel: c}
+}
+
+// id returns the item id as a string. The specification requires ids to be
+// strings, but enough publishers emit numbers that we accept those too.
+func (ji jsonItem) id() string {
+	if len(ji.ID) == 0 || string(ji.ID) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(ji.ID, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(ji.ID))
+}
+
+// jsonNumber returns a number as a string, or an empty string if it is not a
+// number at all. Sizes and durations are optional, so a publisher getting one
+// wrong should not cost us the rest of the feed.
+func jsonNumber(raw json.RawMessage) string {
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return ""
+	}
+	return n.String()
+}
+
+// jsonAuthors returns the authors of a feed or item, preferring the 1.1 authors
+// array over the deprecated 1.0 author object.
+func jsonAuthors(authors []jsonAuthor, author *jsonAuthor) []Person {
+	if len(authors) == 0 && author != nil {
+		authors = []jsonAuthor{*author}
+	}
+	var people []Person
+	for _, a := range authors {
+		people = append(people, Person{Name: a.Name, URL: a.URL, Avatar: a.Avatar})
+	}
+	return people
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSON(t *testing.T) {
+	feed, err := LoadFile(filepath.Join("..", "testdata", "jsonfeed.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if feed.Format != FormatJSON {
+		t.Errorf("expected format %q, got %q", FormatJSON, feed.Format)
+	}
+
+	c := feed.Channel
+	if want := "Example JSON Feed"; c.Title != want {
+		t.Errorf("expected title %q, got %q", want, c.Title)
+	}
+	if want := "https://example.org/"; c.Link != want {
+		t.Errorf("expected link %q, got %q", want, c.Link)
+	}
+	if want := "https://example.org/icon.png"; c.Image != want {
+		t.Errorf("expected image %q, got %q", want, c.Image)
+	}
+
+	if len(c.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(c.Items))
+	}
+
+	first := c.Items[0]
+	if want := "https://example.org/2019/04/08/first"; first.Link != want {
+		t.Errorf("expected link %q, got %q", want, first.Link)
+	}
+	if want := "<p>Hello, world.</p>"; first.Content != want {
+		t.Errorf("expected content %q, got %q", want, first.Content)
+	}
+	if want := "A greeting."; first.Description != want {
+		t.Errorf("expected description %q, got %q", want, first.Description)
+	}
+	if want := "Jane Doe"; first.Author != want {
+		t.Errorf("expected author %q, got %q", want, first.Author)
+	}
+
+	second := c.Items[1]
+	if want := "2"; second.GUID.Value != want {
+		t.Errorf("expected guid %q, got %q", want, second.GUID.Value)
+	}
+	if want := "https://elsewhere.example.com/article"; second.Link != want {
+		t.Errorf("expected link %q, got %q", want, second.Link)
+	}
+	if want := "2019-04-07T08:15:00-07:00"; second.PublicationDate != want {
+		t.Errorf("expected publication date %q, got %q", want, second.PublicationDate)
+	}
+	if want := "Worth reading."; second.Content != want {
+		t.Errorf("expected content %q, got %q", want, second.Content)
+	}
+	if want := "John Smith"; second.Author != want {
+		t.Errorf("expected author %q, got %q", want, second.Author)
+	}
+}
+
+func TestLoadURLContentType(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "jsonfeed.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	feed, err := LoadURL(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Format != FormatJSON {
+		t.Errorf("expected format %q, got %q", FormatJSON, feed.Format)
+	}
+	if want := "Example JSON Feed"; feed.Channel.Title != want {
+		t.Errorf("expected title %q, got %q", want, feed.Channel.Title)
+	}
+}
+
+func TestLoadJSONItem(t *testing.T) {
+	testcases := []struct {
+		name     string
+		item     string
+		content  string
+		length   string
+		duration string
+	}{
+		{
+			name:    "text content",
+			item:    `{"id": "1", "content_text": "a < b && c"}`,
+			content: "a &lt; b &amp;&amp; c",
+		},
+		{
+			name:    "html content",
+			item:    `{"id": "1", "content_html": "<p>a &lt; b</p>", "content_text": "a < b"}`,
+			content: "<p>a &lt; b</p>",
+		},
+		{
+			name:     "numbers",
+			item:     `{"id": "1", "attachments": [{"url": "a.mp3", "size_in_bytes": 1024, "duration_in_seconds": 61.5}]}`,
+			length:   "1024",
+			duration: "61.5",
+		},
+		{
+			name:     "quoted numbers",
+			item:     `{"id": "1", "attachments": [{"url": "a.mp3", "size_in_bytes": "1024", "duration_in_seconds": "61"}]}`,
+			length:   "1024",
+			duration: "61",
+		},
+		{
+			name: "not numbers",
+			item: `{"id": "1", "attachments": [{"url": "a.mp3", "size_in_bytes": "unknown", "duration_in_seconds": {"minutes": 1}}]}`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := `{"version": "https://jsonfeed.org/version/1.1", "title": "T", "items": [` + tc.item + `]}`
+			feed, err := Load(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(feed.Channel.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(feed.Channel.Items))
+			}
+			item := feed.Channel.Items[0]
+			if item.Content != tc.content {
+				t.Errorf("expected content %q, got %q", tc.content, item.Content)
+			}
+			var length string
+			if len(item.Enclosures) > 0 {
+				length = item.Enclosures[0].Length
+			}
+			if length != tc.length {
+				t.Errorf("expected length %q, got %q", tc.length, length)
+			}
+			if item.Duration != tc.duration {
+				t.Errorf("expected duration %q, got %q", tc.duration, item.Duration)
+			}
+		})
+	}
+}
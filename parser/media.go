@@ -0,0 +1,51 @@
+package parser
+
+// Media holds the Media RSS (http://search.yahoo.com/mrss/) elements of an
+// item. It is embedded in both Item and Atom entries, as YouTube and others
+// use Media RSS inside Atom feeds. The fields are prefixed so that they do not
+// shadow the item's own title and description.
+type Media struct {
+	MediaContents    []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroups      []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	MediaThumbnails  []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaDescription string           `xml:"http://search.yahoo.com/mrss/ description"`
+	MediaCredits     []MediaCredit    `xml:"http://search.yahoo.com/mrss/ credit"`
+}
+
+// MediaGroup bundles alternative renditions of the same media object. Elements
+// set on the group apply to every content element within it.
+type MediaGroup struct {
+	Contents    []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Title       string           `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string           `xml:"http://search.yahoo.com/mrss/ description"`
+	Thumbnails  []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Credits     []MediaCredit    `xml:"http://search.yahoo.com/mrss/ credit"`
+}
+
+type MediaContent struct {
+	URL         string           `xml:"url,attr"`
+	Type        string           `xml:"type,attr"`
+	Medium      string           `xml:"medium,attr"`
+	FileSize    string           `xml:"fileSize,attr"`
+	Duration    string           `xml:"duration,attr"`
+	Width       string           `xml:"width,attr"`
+	Height      string           `xml:"height,attr"`
+	IsDefault   string           `xml:"isDefault,attr"`
+	Title       string           `xml:"http://search.yahoo.com/mrss/ title"`
+	Description string           `xml:"http://search.yahoo.com/mrss/ description"`
+	Thumbnails  []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	Credits     []MediaCredit    `xml:"http://search.yahoo.com/mrss/ credit"`
+}
+
+type MediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+	Time   string `xml:"time,attr"`
+}
+
+type MediaCredit struct {
+	Role   string `xml:"role,attr"`
+	Scheme string `xml:"scheme,attr"`
+	Name   string `xml:",chardata"`
+}
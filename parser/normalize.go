@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/haleyrc/rss/markup"
+)
+
+// invisible are format characters that render as nothing but break searching
+// and comparing text. Joiners are deliberately absent, since they change how
+// emoji and some scripts are displayed.
+var invisible = map[rune]bool{
+	'\u00ad': true, // soft hyphen
+	'\u180e': true, // mongolian vowel separator
+	'\u200b': true, // zero width space
+	'\u2060': true, // word joiner
+	'\ufeff': true, // zero width no-break space, or a stray byte order mark
+}
+
+// normalize cleans up every text field of the feed so that the rest of the
+// application sees the same text whichever format it was published in. Plain
+// text is NFC normalised, stripped of invisible and control characters and
+// has its whitespace collapsed. Titles may also contain entities, which are
+// decoded. HTML and URLs are only normalised and trimmed, since whitespace may
+// be significant in them.
+func (f *Feed) normalize() {
+	c := &f.Channel
+	c.Title = normalizeTitle(c.Title)
+	c.Description = normalizeText(c.Description)
+	c.Link = normalizeValue(c.Link)
+	c.Image = normalizeValue(c.Image)
+	c.Base = normalizeValue(c.Base)
+	normalizeCategories(c.Categories)
+	c.Authors = normalizePeople(c.Authors)
+	c.TTL = normalizeValue(c.TTL)
+	for i := range c.SkipHours {
+		c.SkipHours[i] = normalizeValue(c.SkipHours[i])
+	}
+	for i := range c.SkipDays {
+		c.SkipDays[i] = normalizeValue(c.SkipDays[i])
+	}
+	c.UpdatePeriod = normalizeValue(c.UpdatePeriod)
+	c.UpdateFrequency = normalizeValue(c.UpdateFrequency)
+	c.Cloud.Domain = normalizeValue(c.Cloud.Domain)
+	c.Cloud.Port = normalizeValue(c.Cloud.Port)
+	c.Cloud.Path = normalizeValue(c.Cloud.Path)
+	c.Cloud.RegisterProcedure = normalizeValue(c.Cloud.RegisterProcedure)
+	c.Cloud.Protocol = normalizeValue(c.Cloud.Protocol)
+	for i := range c.Links {
+		l := &c.Links[i]
+		l.Href = normalizeValue(l.Href)
+		l.Rel = normalizeValue(l.Rel)
+		l.Type = normalizeValue(l.Type)
+	}
+
+	for i := range c.Items {
+		item := &c.Items[i]
+		item.GUID.Value = normalizeValue(item.GUID.Value)
+		item.Title = normalizeTitle(item.Title)
+		item.Link = normalizeValue(item.Link)
+		item.Base = normalizeValue(item.Base)
+		item.PublicationDate = normalizeText(item.PublicationDate)
+		item.Description = normalizeHTML(item.Description)
+		item.Content = normalizeHTML(item.Content)
+		item.Author = normalizeText(item.Author)
+		item.Duration = normalizeValue(item.Duration)
+		item.Episode = normalizeValue(item.Episode)
+		item.Image.Href = normalizeValue(item.Image.Href)
+		for j := range item.Enclosures {
+			e := &item.Enclosures[j]
+			e.URL = normalizeValue(e.URL)
+			e.Type = normalizeValue(e.Type)
+			e.Length = normalizeValue(e.Length)
+		}
+		item.MediaDescription = normalizeText(item.MediaDescription)
+		normalizeCategories(item.Categories)
+		item.Authors = normalizePeople(item.Authors)
+	}
+}
+
+func normalizeCategories(categories []Category) {
+	for i := range categories {
+		categories[i].Name = normalizeText(categories[i].Name)
+		categories[i].Domain = normalizeValue(categories[i].Domain)
+	}
+}
+
+// normalizePeople returns a normalised copy of people, since items may share
+// the authors of their channel.
+func normalizePeople(people []Person) []Person {
+	var normalized []Person
+	for _, p := range people {
+		normalized = append(normalized, Person{
+			Name:   normalizeText(p.Name),
+			Email:  normalizeValue(p.Email),
+			URL:    normalizeValue(p.URL),
+			Avatar: normalizeValue(p.Avatar),
+		})
+	}
+	return normalized
+}
+
+// normalizeTitle returns the text of a title with entities decoded.
+// Publishers escape titles inconsistently, so an entity that survived XML
+// decoding was almost certainly meant to be shown as the character it stands
+// for. Anything that looks like markup is kept, since titles are plain text
+// unless the format says otherwise; those that are not have already been
+// through markupText.
+func normalizeTitle(s string) string {
+	return normalizeText(html.UnescapeString(s))
+}
+
+// markupText returns the text of an HTML fragment with its markup removed and
+// entities decoded.
+func markupText(s string) string {
+	var b strings.Builder
+	for _, tok := range markup.Tokenize(s) {
+		if tok.Type == markup.TextToken {
+			b.WriteString(tok.Data)
+		}
+	}
+	return b.String()
+}
+
+// normalizeText normalises plain text and collapses runs of whitespace,
+// including line breaks, into single spaces.
+func normalizeText(s string) string {
+	s = normalizeValue(s)
+	var b strings.Builder
+	space := false
+	for _, c := range s {
+		if unicode.IsSpace(c) {
+			space = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// normalizeHTML normalises markup, leaving its whitespace alone apart from
+// trimming it, since it is significant inside elements such as <pre>. Soft
+// hyphens are kept as they are a legitimate hint to the browser.
+func normalizeHTML(s string) string {
+	return strings.TrimSpace(strings.Map(func(c rune) rune {
+		if c == '\u00ad' || isVisible(c) {
+			return c
+		}
+		return -1
+	}, norm.NFC.String(s)))
+}
+
+// normalizeValue normalises a single value such as a URL or a date, removing
+// invisible characters and surrounding whitespace.
+func normalizeValue(s string) string {
+	return strings.TrimSpace(strings.Map(func(c rune) rune {
+		if isVisible(c) {
+			return c
+		}
+		return -1
+	}, norm.NFC.String(s)))
+}
+
+// isVisible reports whether c should be kept in normalised text. Whitespace is
+// kept so that it can be collapsed or trimmed by the caller.
+func isVisible(c rune) bool {
+	if invisible[c] {
+		return false
+	}
+	return !unicode.IsControl(c) || unicode.IsSpace(c)
+}
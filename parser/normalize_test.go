@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain", input: "Hello, world", want: "Hello, world"},
+		{name: "whitespace", input: "\n\t  Hello,\n   world \r\n", want: "Hello, world"},
+		{name: "non-breaking space", input: "Hello,\u00a0 world", want: "Hello, world"},
+		{name: "angle brackets", input: "Vec<T> is great", want: "Vec<T> is great"},
+		{name: "tag-like text", input: "Using <title> tags", want: "Using <title> tags"},
+		{name: "entities", input: "Fish &amp; Chips &mdash; &#8220;quoted&#8221;", want: "Fish & Chips \u2014 \u201cquoted\u201d"},
+		{name: "bare less-than", input: "1 < 2", want: "1 < 2"},
+		{name: "nfc", input: "Cafe\u0301", want: "Caf\u00e9"},
+		{name: "invisible", input: "zero\u200bwidth\ufeff soft\u00adhyphen", want: "zerowidth softhyphen"},
+		{name: "control", input: "bell\x07 and\x1b escape", want: "bell and escape"},
+		{name: "joiner", input: "family \U0001F468\u200d\U0001F469", want: "family \U0001F468\u200d\U0001F469"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := normalizeTitle(tc.input); got != tc.want {
+				t.Errorf("wanted %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadAtomTitleType(t *testing.T) {
+	testcases := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{name: "text", title: `<title>Vec&lt;T&gt; &amp; friends</title>`, want: "Vec<T> & friends"},
+		{name: "html", title: `<title type="html">&lt;b&gt;Bold&lt;/b&gt; &amp;amp; brave</title>`, want: "Bold & brave"},
+		{name: "xhtml", title: `<title type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><em>Very</em> nice</div></title>`, want: "Very nice"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := `<feed xmlns="http://www.w3.org/2005/Atom">` + tc.title + `</feed>`
+			feed, err := Load(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := feed.Channel.Title; got != tc.want {
+				t.Errorf("wanted %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeHTML(t *testing.T) {
+	input := "\n  <pre>line one\n    line two</pre>\u200b<p>Cafe\u0301 hy\u00adphen</p>  \n"
+	want := "<pre>line one\n    line two</pre><p>Caf\u00e9 hy\u00adphen</p>"
+	if got := normalizeHTML(input); got != want {
+		t.Errorf("wanted %q, got %q", want, got)
+	}
+}
+
+func TestLoadNormalized(t *testing.T) {
+	testcases := []struct {
+		file  string
+		title string
+	}{
+		{file: "checkly.xml", title: "Our Stripe Billing implementation and the one webhook to rule them all"},
+		{file: "atom.xml", title: "Atom-Powered Robots Run Amok"},
+		{file: "rdf.xml", title: "On the Decoding of Feeds"},
+		{file: "jsonfeed.json", title: "First Post"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.file, func(t *testing.T) {
+			feed, err := LoadFile(filepath.Join("..", "testdata", tc.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := feed.Channel.Title; got != strings.TrimSpace(got) || strings.Contains(got, "\n") {
+				t.Errorf("expected channel title to be normalised, got %q", got)
+			}
+			if len(feed.Channel.Items) == 0 {
+				t.Fatalf("expected items, got none")
+			}
+			if got := feed.Channel.Items[0].Title; got != tc.title {
+				t.Errorf("expected title %q, got %q", tc.title, got)
+			}
+		})
+	}
+}
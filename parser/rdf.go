@@ -0,0 +1,100 @@
+package parser
+
+import "encoding/xml"
+
+// rdfFeed is the wire representation of an RSS 1.0 document. Unlike RSS 2.0,
+// the channel, image and items are all siblings under the <rdf:RDF> root and
+// the channel only refers to them by URI.
+type rdfFeed struct {
+	XMLName xml.Name   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF"`
+	Base    string     `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Channel rdfChannel `xml:"http://purl.org/rss/1.0/ channel"`
+	Image   rdfImage   `xml:"http://purl.org/rss/1.0/ image"`
+	Items   []rdfItem  `xml:"http://purl.org/rss/1.0/ item"`
+}
+
+type rdfChannel struct {
+	Title       string      `xml:"http://purl.org/rss/1.0/ title"`
+	Link        string      `xml:"http://purl.org/rss/1.0/ link"`
+	Description string      `xml:"http://purl.org/rss/1.0/ description"`
+	Image       rdfResource `xml:"http://purl.org/rss/1.0/ image"`
+	Date        string      `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Subjects    []string    `xml:"http://purl.org/dc/elements/1.1/ subject"`
+
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+type rdfResource struct {
+	Resource string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# resource,attr"`
+}
+
+type rdfImage struct {
+	URL   string `xml:"http://purl.org/rss/1.0/ url"`
+	Title string `xml:"http://purl.org/rss/1.0/ title"`
+	Link  string `xml:"http://purl.org/rss/1.0/ link"`
+}
+
+type rdfItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"http://purl.org/rss/1.0/ title"`
+	Link        string   `xml:"http://purl.org/rss/1.0/ link"`
+	Description string   `xml:"http://purl.org/rss/1.0/ description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+func loadRDF(b []byte) (Feed, error) {
+	var rf rdfFeed
+	if err := newDecoder(b).Decode(&rf); err != nil {
+		return Feed{}, err
+	}
+	return rf.toFeed(), nil
+}
+
+func (rf rdfFeed) toFeed() Feed {
+	c := Channel{
+		Title:       rf.Channel.Title,
+		Description: rf.Channel.Description,
+		Link:        rf.Channel.Link,
+		Image:       firstNonEmpty(rf.Image.URL, rf.Channel.Image.Resource),
+		Base:        rf.Base,
+		Categories:  rdfSubjects(rf.Channel.Subjects),
+
+		UpdatePeriod:    rf.Channel.UpdatePeriod,
+		UpdateFrequency: rf.Channel.UpdateFrequency,
+	}
+	for _, ri := range rf.Items {
+		c.Items = append(c.Items, Item{
+			GUID:            GUID{Value: ri.About, IsPermaLink: "false"},
+			Title:           ri.Title,
+			Link:            firstNonEmpty(ri.Link, ri.About),
+			PublicationDate: ri.Date,
+			Description:     ri.Description,
+			Content:         ri.Content,
+			Author:          ri.Creator,
+			Authors:         rdfCreators(ri.Creator),
+			Categories:      rdfSubjects(ri.Subjects),
+		})
+	}
+	return Feed{Format: FormatRDF, Channel: c}
+}
+
+// rdfSubjects maps Dublin Core subjects, which RSS 1.0 feeds use in place of
+// categories, onto categories.
+func rdfSubjects(subjects []string) []Category {
+	var categories []Category
+	for _, s := range subjects {
+		categories = append(categories, Category{Name: s})
+	}
+	return categories
+}
+
+func rdfCreators(creator string) []Person {
+	if p := parsePerson(creator); p != (Person{}) {
+		return []Person{p}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRDF(t *testing.T) {
+	feed, err := LoadFile(filepath.Join("..", "testdata", "rdf.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if feed.Format != FormatRDF {
+		t.Errorf("expected format %q, got %q", FormatRDF, feed.Format)
+	}
+
+	c := feed.Channel
+	if want := "Example Journal"; c.Title != want {
+		t.Errorf("expected title %q, got %q", want, c.Title)
+	}
+	if want := "Latest articles from the Example Journal."; c.Description != want {
+		t.Errorf("expected description %q, got %q", want, c.Description)
+	}
+	if want := "https://example.org/journal/"; c.Link != want {
+		t.Errorf("expected link %q, got %q", want, c.Link)
+	}
+	if want := "https://example.org/journal/logo.gif"; c.Image != want {
+		t.Errorf("expected image %q, got %q", want, c.Image)
+	}
+
+	if len(c.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(c.Items))
+	}
+
+	first := c.Items[0]
+	if want := "On the Decoding of Feeds"; first.Title != want {
+		t.Errorf("expected title %q, got %q", want, first.Title)
+	}
+	if want := "2019-04-08T09:30:00+01:00"; first.PublicationDate != want {
+		t.Errorf("expected publication date %q, got %q", want, first.PublicationDate)
+	}
+	if want := "A. Researcher"; first.Author != want {
+		t.Errorf("expected author %q, got %q", want, first.Author)
+	}
+
+	second := c.Items[1]
+	if want := "https://example.org/journal/articles/2"; second.Link != want {
+		t.Errorf("expected link to fall back to rdf:about %q, got %q", want, second.Link)
+	}
+	if want := "<p>Full text.</p>"; second.Content != want {
+		t.Errorf("expected content %q, got %q", want, second.Content)
+	}
+}
@@ -0,0 +1,423 @@
+package parser
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// RepairKind identifies a class of well-formedness error fixed by Recover.
+type RepairKind string
+
+const (
+	// RepairAmpersand escapes an ampersand that does not start an entity.
+	RepairAmpersand RepairKind = "unescaped ampersand"
+	// RepairLessThan escapes a less-than sign that does not start markup.
+	RepairLessThan RepairKind = "unescaped less-than"
+	// RepairHTMLEntity replaces a named HTML entity, such as &nbsp;, which
+	// XML does not define, with the equivalent character reference.
+	RepairHTMLEntity RepairKind = "html entity"
+	// RepairControlCharacter removes a character, or a reference to one,
+	// that may not appear in an XML document.
+	RepairControlCharacter RepairKind = "control character"
+	// RepairCDATA terminates a CDATA section that was never closed.
+	RepairCDATA RepairKind = "unterminated cdata"
+	// RepairUnclosedElement closes an element that was still open when its
+	// parent, or the document, ended.
+	RepairUnclosedElement RepairKind = "unclosed element"
+	// RepairStrayEndTag removes an end tag that matches no open element.
+	RepairStrayEndTag RepairKind = "stray end tag"
+)
+
+// Repair records how many times a kind of repair was applied to a document.
+type Repair struct {
+	Kind  RepairKind `json:"kind"`
+	Count int        `json:"count"`
+}
+
+func (r Repair) String() string {
+	return fmt.Sprintf("%s (%d)", r.Kind, r.Count)
+}
+
+// xmlEntities are the only named entities XML defines.
+var xmlEntities = map[string]bool{
+	"amp":  true,
+	"apos": true,
+	"gt":   true,
+	"lt":   true,
+	"quot": true,
+}
+
+// Recover rewrites a malformed XML document so that it can be decoded. Markup
+// is copied as is and the text between it is rewritten in the same way as
+// ProcessElementText rewrites element text, fixing stray ampersands and
+// less-than signs, HTML entities and control characters. Unterminated CDATA
+// sections and unclosed elements are closed, and end tags that close nothing
+// are dropped. The repairs made are returned in the order they were first
+// needed; if there are none the document was either well-formed or broken in a
+// way we can not fix.
+func Recover(b []byte) ([]byte, []Repair) {
+	r := &recoverer{input: string(b)}
+	r.run()
+	return []byte(r.output.String()), r.repairs
+}
+
+type recoverer struct {
+	input   string
+	output  strings.Builder
+	open    []string
+	repairs []Repair
+}
+
+func (r *recoverer) repaired(kind RepairKind) {
+	for i := range r.repairs {
+		if r.repairs[i].Kind == kind {
+			r.repairs[i].Count++
+			return
+		}
+	}
+	r.repairs = append(r.repairs, Repair{Kind: kind, Count: 1})
+}
+
+func (r *recoverer) run() {
+	s := r.input
+	i := 0
+	for i < len(s) {
+		lt := strings.IndexByte(s[i:], '<')
+		if lt == -1 {
+			r.output.WriteString(r.text(s[i:]))
+			break
+		}
+		r.output.WriteString(r.text(s[i : i+lt]))
+		i += lt
+
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, "<![CDATA["):
+			i += r.cdata(rest)
+		case strings.HasPrefix(rest, "<!--"):
+			i += r.until(rest, "-->")
+		case strings.HasPrefix(rest, "<?"):
+			i += r.until(rest, "?>")
+		case strings.HasPrefix(rest, "<!"):
+			i += r.doctype(rest)
+		case len(rest) > 1 && rest[1] == '/' && len(rest) > 2 && isNameStart(rest[2]):
+			i += r.endTag(rest)
+		case len(rest) > 1 && isNameStart(rest[1]):
+			i += r.startTag(rest)
+		default:
+			r.repaired(RepairLessThan)
+			r.output.WriteString("&lt;")
+			i++
+		}
+	}
+
+	for len(r.open) > 0 {
+		r.repaired(RepairUnclosedElement)
+		r.closeTop()
+	}
+}
+
+// text fixes the character data between two pieces of markup.
+func (r *recoverer) text(s string) string {
+	if !strings.Contains(s, "&") && !hasControlCharacters(s) {
+		return s
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case c == '&':
+			ref, n := r.reference(s[i:])
+			out.WriteString(ref)
+			i += n
+		case !isXMLChar(c):
+			r.repaired(RepairControlCharacter)
+			i += size
+		default:
+			out.WriteString(s[i : i+size])
+			i += size
+		}
+	}
+	return out.String()
+}
+
+// reference fixes the entity or character reference at the start of s, which
+// begins with an ampersand, and returns its replacement along with the number
+// of bytes of s it consumed.
+func (r *recoverer) reference(s string) (string, int) {
+	semi := strings.IndexByte(s, ';')
+	if semi == -1 || semi > 32 {
+		r.repaired(RepairAmpersand)
+		return "&amp;", 1
+	}
+	name := s[1:semi]
+
+	if strings.HasPrefix(name, "#") {
+		c, ok := parseCharRef(name[1:])
+		switch {
+		case !ok:
+			r.repaired(RepairAmpersand)
+			return "&amp;", 1
+		case !isXMLChar(c):
+			r.repaired(RepairControlCharacter)
+			return "", semi + 1
+		default:
+			return s[:semi+1], semi + 1
+		}
+	}
+
+	if !isName(name) {
+		r.repaired(RepairAmpersand)
+		return "&amp;", 1
+	}
+	if xmlEntities[name] {
+		return s[:semi+1], semi + 1
+	}
+	entity := s[:semi+1]
+	if unescaped := html.UnescapeString(entity); unescaped != entity {
+		r.repaired(RepairHTMLEntity)
+		var refs strings.Builder
+		for _, c := range unescaped {
+			fmt.Fprintf(&refs, "&#%d;", c)
+		}
+		return refs.String(), semi + 1
+	}
+	r.repaired(RepairAmpersand)
+	return "&amp;", 1
+}
+
+// cdata copies the CDATA section at the start of s. A section that is never
+// terminated, or that runs into the start of another section, is closed at
+// the end tag of the element it appears in.
+func (r *recoverer) cdata(s string) int {
+	const start, end = "<![CDATA[", "]]>"
+	body := s[len(start):]
+
+	stop := strings.Index(body, end)
+	next := strings.Index(body, start)
+	if stop != -1 && (next == -1 || stop < next) {
+		r.output.WriteString(start)
+		r.output.WriteString(r.stripControl(body[:stop]))
+		r.output.WriteString(end)
+		return len(start) + stop + len(end)
+	}
+
+	r.repaired(RepairCDATA)
+	stop = len(body)
+	if next != -1 {
+		stop = next
+	}
+	if n := len(r.open); n > 0 {
+		if close := strings.Index(body[:stop], "</"+r.open[n-1]); close != -1 {
+			stop = close
+		}
+	}
+	r.output.WriteString(start)
+	r.output.WriteString(r.stripControl(body[:stop]))
+	r.output.WriteString(end)
+	return len(start) + stop
+}
+
+func (r *recoverer) stripControl(s string) string {
+	if !hasControlCharacters(s) {
+		return s
+	}
+	return strings.Map(func(c rune) rune {
+		if !isXMLChar(c) {
+			r.repaired(RepairControlCharacter)
+			return -1
+		}
+		return c
+	}, s)
+}
+
+// until copies s up to and including end, or all of s if end never appears.
+func (r *recoverer) until(s, end string) int {
+	n := strings.Index(s, end)
+	if n == -1 {
+		r.output.WriteString(s)
+		r.output.WriteString(end)
+		return len(s)
+	}
+	r.output.WriteString(s[:n+len(end)])
+	return n + len(end)
+}
+
+// doctype copies a document type declaration, which may contain an internal
+// subset in square brackets.
+func (r *recoverer) doctype(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '>':
+			if depth <= 0 {
+				r.output.WriteString(s[:i+1])
+				return i + 1
+			}
+		}
+	}
+	r.output.WriteString(s)
+	r.output.WriteString(">")
+	return len(s)
+}
+
+// startTag copies the start tag at the beginning of s and records the element
+// as open unless the tag closes itself.
+func (r *recoverer) startTag(s string) int {
+	name, _ := readXMLName(s[1:])
+	n := tagLength(s)
+	tag := s[:n]
+	if !strings.HasSuffix(tag, ">") {
+		tag += ">"
+	}
+	r.output.WriteString(r.attributes(tag))
+	if !strings.HasSuffix(tag, "/>") {
+		r.open = append(r.open, name)
+	}
+	return n
+}
+
+// attributes fixes references in the attribute values of a tag.
+func (r *recoverer) attributes(tag string) string {
+	if !strings.Contains(tag, "&") && !hasControlCharacters(tag) {
+		return tag
+	}
+	return r.text(tag)
+}
+
+// endTag copies the end tag at the beginning of s. Elements left open inside
+// the one being closed are closed first, and an end tag that matches no open
+// element is dropped.
+func (r *recoverer) endTag(s string) int {
+	name, _ := readXMLName(s[2:])
+	n := tagLength(s)
+
+	match := -1
+	for i := len(r.open) - 1; i >= 0; i-- {
+		if r.open[i] == name {
+			match = i
+			break
+		}
+	}
+	if match == -1 {
+		r.repaired(RepairStrayEndTag)
+		return n
+	}
+	for len(r.open) > match+1 {
+		r.repaired(RepairUnclosedElement)
+		r.closeTop()
+	}
+	r.closeTop()
+	return n
+}
+
+func (r *recoverer) closeTop() {
+	name := r.open[len(r.open)-1]
+	r.open = r.open[:len(r.open)-1]
+	r.output.WriteString("</" + name + ">")
+}
+
+// tagLength returns the length of the tag at the start of s, up to and
+// including the closing '>', skipping over quoted attribute values.
+func tagLength(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		case c == '<':
+			// The tag was never closed and we have run into the next
+			// one.
+			return i
+		}
+	}
+	return len(s)
+}
+
+func readXMLName(s string) (string, int) {
+	i := 0
+	for i < len(s) && isNameChar(s[i]) {
+		i++
+	}
+	return s[:i], i
+}
+
+func parseCharRef(s string) (rune, bool) {
+	base := 10
+	if strings.HasPrefix(s, "x") || strings.HasPrefix(s, "X") {
+		base = 16
+		s = s[1:]
+	}
+	if s == "" || len(s) > 8 {
+		return 0, false
+	}
+	var c rune
+	for i := 0; i < len(s); i++ {
+		var d rune
+		switch ch := s[i]; {
+		case '0' <= ch && ch <= '9':
+			d = rune(ch - '0')
+		case base == 16 && 'a' <= ch && ch <= 'f':
+			d = rune(ch-'a') + 10
+		case base == 16 && 'A' <= ch && ch <= 'F':
+			d = rune(ch-'A') + 10
+		default:
+			return 0, false
+		}
+		c = c*rune(base) + d
+	}
+	return c, true
+}
+
+func isName(s string) bool {
+	if s == "" || !isNameStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNameStart and isNameChar only consider ASCII, which covers every element
+// name found in feeds, while treating any other byte of a multi-byte character
+// as part of a name.
+func isNameStart(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c == ':' || c >= 0x80
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || ('0' <= c && c <= '9') || c == '-' || c == '.'
+}
+
+// isXMLChar reports whether c may appear in an XML 1.0 document.
+func isXMLChar(c rune) bool {
+	return c == '\t' || c == '\n' || c == '\r' ||
+		(0x20 <= c && c <= 0xD7FF) ||
+		(0xE000 <= c && c <= 0xFFFD) ||
+		(0x10000 <= c && c <= 0x10FFFF)
+}
+
+func hasControlCharacters(s string) bool {
+	for _, c := range s {
+		if !isXMLChar(c) {
+			return true
+		}
+	}
+	return false
+}
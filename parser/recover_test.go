@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		want    string
+		repairs []Repair
+	}{
+		{
+			name:  "well-formed",
+			input: `<rss><channel><title>Fish &amp; Chips &#8212; &#x2014;</title></channel></rss>`,
+			want:  `<rss><channel><title>Fish &amp; Chips &#8212; &#x2014;</title></channel></rss>`,
+		},
+		{
+			name:    "unescaped ampersand",
+			input:   `<title>Fish & Chips &unknown; AT&T</title><link>http://example.com/?a=1&b=2</link>`,
+			want:    `<title>Fish &amp; Chips &amp;unknown; AT&amp;T</title><link>http://example.com/?a=1&amp;b=2</link>`,
+			repairs: []Repair{{Kind: RepairAmpersand, Count: 4}},
+		},
+		{
+			name:    "html entities",
+			input:   `<title>Fish&nbsp;&mdash;&nbsp;Chips &eacute;</title>`,
+			want:    `<title>Fish&#160;&#8212;&#160;Chips &#233;</title>`,
+			repairs: []Repair{{Kind: RepairHTMLEntity, Count: 4}},
+		},
+		{
+			name:    "control characters",
+			input:   "<title>Bell\x07 and &#0;null &#x1b;escape</title><description><![CDATA[back\x08space]]></description>",
+			want:    "<title>Bell and null escape</title><description><![CDATA[backspace]]></description>",
+			repairs: []Repair{{Kind: RepairControlCharacter, Count: 4}},
+		},
+		{
+			name:    "unterminated cdata",
+			input:   `<item><description><![CDATA[<p>never closed</p></description><title><![CDATA[Fine]]></title></item>`,
+			want:    `<item><description><![CDATA[<p>never closed</p>]]></description><title><![CDATA[Fine]]></title></item>`,
+			repairs: []Repair{{Kind: RepairCDATA, Count: 1}},
+		},
+		{
+			name:    "unclosed elements",
+			input:   `<rss><channel><description>line<br>break</description><title>Truncated`,
+			want:    `<rss><channel><description>line<br>break</br></description><title>Truncated</title></channel></rss>`,
+			repairs: []Repair{{Kind: RepairUnclosedElement, Count: 4}},
+		},
+		{
+			name:    "stray end tag",
+			input:   `<title>Hello</p></title>`,
+			want:    `<title>Hello</title>`,
+			repairs: []Repair{{Kind: RepairStrayEndTag, Count: 1}},
+		},
+		{
+			name:    "unescaped less-than",
+			input:   `<title>1 < 2</title>`,
+			want:    `<title>1 &lt; 2</title>`,
+			repairs: []Repair{{Kind: RepairLessThan, Count: 1}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, repairs := Recover([]byte(tc.input))
+			if string(got) != tc.want {
+				t.Errorf("wanted %q, got %q", tc.want, got)
+			}
+			if len(repairs) != len(tc.repairs) {
+				t.Fatalf("expected repairs %v, got %v", tc.repairs, repairs)
+			}
+			for i := range repairs {
+				if repairs[i] != tc.repairs[i] {
+					t.Errorf("expected repair %v, got %v", tc.repairs[i], repairs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadRecovered(t *testing.T) {
+	input := "<?xml version=\"1.0\"?>\n" +
+		"<rss version=\"2.0\"><channel>" +
+		"<title>Fish & Chips&nbsp;Weekly\x0c</title>" +
+		"<description>All about fish &mdash; and chips</description>" +
+		"<link>http://example.com/?a=1&b=2</link>" +
+		"<item><title>First</title><description><![CDATA[<p>unterminated</description></item>" +
+		"<item><title>Second</title>"
+
+	feed, err := Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "Fish & Chips Weekly"; feed.Channel.Title != want {
+		t.Errorf("expected title %q, got %q", want, feed.Channel.Title)
+	}
+	if want := "All about fish — and chips"; feed.Channel.Description != want {
+		t.Errorf("expected description %q, got %q", want, feed.Channel.Description)
+	}
+	if want := "http://example.com/?a=1&b=2"; feed.Channel.Link != want {
+		t.Errorf("expected link %q, got %q", want, feed.Channel.Link)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(feed.Channel.Items))
+	}
+	if want := "<p>unterminated"; feed.Channel.Items[0].Description != want {
+		t.Errorf("expected description %q, got %q", want, feed.Channel.Items[0].Description)
+	}
+
+	kinds := make(map[RepairKind]bool)
+	for _, r := range feed.Repairs {
+		kinds[r.Kind] = true
+	}
+	for _, want := range []RepairKind{RepairAmpersand, RepairHTMLEntity, RepairControlCharacter, RepairCDATA, RepairUnclosedElement} {
+		if !kinds[want] {
+			t.Errorf("expected repair %q to be recorded, got %v", want, feed.Repairs)
+		}
+	}
+
+	wellFormed, err := LoadFile(filepath.Join("..", "testdata", "hackernews.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(wellFormed.Repairs) != 0 {
+		t.Errorf("expected no repairs, got %v", wellFormed.Repairs)
+	}
+}
+
+func TestLoadUnrecoverable(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+	}{
+		{name: "empty", input: ``},
+		{name: "not markup", input: `just some text & more`},
+		{name: "unknown root", input: `<html><body>Fish & Chips</body></html>`},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(strings.NewReader(tc.input)); err == nil {
+				t.Fatalf("expected error, but got none")
+			}
+		})
+	}
+}
@@ -1,19 +1,23 @@
-// Package parser parses
+// Package parser decodes RSS 2.0, RSS 1.0 (RDF), Atom and JSON Feed documents
+// into a common Channel.
 //
-// TODO
-//
-//   - Implement custom unmarshaling to trim space
+// Whatever the format, the text of a decoded feed is normalised in the same
+// way: titles are stripped of markup, plain text has its whitespace collapsed,
+// and everything is converted to Unicode NFC with invisible and control
+// characters removed. Callers can rely on fields having no surrounding
+// whitespace.
 package parser
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
 	"io"
-	"net/http"
+	"io/ioutil"
+	"net"
 	"os"
 	"strconv"
 	"strings"
-
-	"github.com/pkg/errors"
 )
 
 const (
@@ -22,40 +26,208 @@ const (
 )
 
 type Feed struct {
+	Format  Format  `xml:"-"`
 	Channel Channel `xml:"channel"`
+
+	// Base is the xml:base of the document element. It is folded into
+	// Channel.Base when the feed is loaded.
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+
+	// Repairs lists the fixes that had to be made to a malformed document
+	// before it could be decoded. It is empty for well-formed feeds.
+	Repairs []Repair `xml:"-"`
 }
 
 type Channel struct {
-	Title       string `xml:"title"`
-	Description string `xml:"description"`
-	Link        string `xml:"Default link"`
-	Image       string `xml:"image>url"`
-	Items       []Item `xml:"item"`
+	Title       string     `xml:"title"`
+	Description string     `xml:"description"`
+	Link        string     `xml:"Default link"`
+	Image       string     `xml:"image>url"`
+	Categories  []Category `xml:"Default category"`
+	Items       []Item     `xml:"item"`
+
+	// ManagingEditor and WebMaster are the RSS bylines for the people
+	// responsible for the channel. Authors holds them in structured form,
+	// along with the authors of Atom and JSON feeds.
+	ManagingEditor string   `xml:"managingEditor"`
+	WebMaster      string   `xml:"webMaster"`
+	Authors        []Person `xml:"-"`
+
+	// TTL, SkipHours and SkipDays are the RSS hints for how often the feed
+	// should be fetched. UpdatePeriod and UpdateFrequency are the
+	// equivalent from the syndication module, which other formats use too.
+	TTL             string   `xml:"ttl"`
+	SkipHours       []string `xml:"skipHours>hour"`
+	SkipDays        []string `xml:"skipDays>day"`
+	UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+
+	// Links are the typed links a feed publishes about itself, such as its
+	// own address. RSS borrows these from Atom as <atom:link>.
+	Links []Link `xml:"http://www.w3.org/2005/Atom link"`
+
+	// Cloud is the rssCloud service that can notify subscribers when the
+	// channel changes, if the feed names one.
+	Cloud Cloud `xml:"cloud"`
+
+	// Base is the URL that relative references in the feed are resolved
+	// against. It starts as the xml:base in scope for the channel, and when
+	// the feed is fetched it is resolved against the address it came from.
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
 }
 
-type Item struct {
-	Title           string `xml:"title"`
-	Link            string `xml:"Default link"`
-	PublicationDate string `xml:"pubDate"`
+// Link is a typed link from a feed to a related resource.
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
 }
 
-func LoadURL(url string) (Feed, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return Feed{}, err
+// SelfLink returns the address the feed says it is published at, or an empty
+// string if it does not say.
+func (c Channel) SelfLink() string {
+	for _, l := range c.Links {
+		if strings.EqualFold(l.Rel, "self") {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+// Cloud is an rssCloud service that notifies registered subscribers when a
+// channel is updated.
+type Cloud struct {
+	Domain            string `xml:"domain,attr"`
+	Port              string `xml:"port,attr"`
+	Path              string `xml:"path,attr"`
+	RegisterProcedure string `xml:"registerProcedure,attr"`
+	Protocol          string `xml:"protocol,attr"`
+}
+
+// RegisterURL returns the address that subscribers register with the cloud
+// at, or an empty string if the cloud does not use the http-post protocol,
+// which is the only one we speak.
+func (c Cloud) RegisterURL() string {
+	if c.Domain == "" || !strings.EqualFold(c.Protocol, "http-post") {
+		return ""
+	}
+	port := c.Port
+	if port == "" {
+		port = "80"
+	}
+	path := c.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
 	}
-	defer resp.Body.Close()
+	return "http://" + net.JoinHostPort(c.Domain, port) + path
+}
+
+// HubLinks returns the addresses of the WebSub hubs that the feed says it
+// publishes updates through.
+func (c Channel) HubLinks() []string {
+	var hubs []string
+	for _, l := range c.Links {
+		if strings.EqualFold(l.Rel, "hub") && strings.TrimSpace(l.Href) != "" {
+			hubs = append(hubs, strings.TrimSpace(l.Href))
+		}
+	}
+	return hubs
+}
+
+// Item is a single entry in a Channel. Title, Description and Author are
+// restricted to the default namespace so that the equivalent iTunes and Media
+// RSS elements, which are frequently present too, don't overwrite them.
+//
+// Author is the name of the first author, or the byline as published for RSS.
+// Authors lists everyone credited with the item in structured form.
+type Item struct {
+	GUID            GUID        `xml:"guid"`
+	Title           string      `xml:"Default title"`
+	Link            string      `xml:"Default link"`
+	PublicationDate string      `xml:"pubDate"`
+	Description     string      `xml:"Default description"`
+	Content         string      `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author          string      `xml:"Default author"`
+	Creators        []string    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Authors         []Person    `xml:"-"`
+	Categories      []Category  `xml:"Default category"`
+	Enclosures      []Enclosure `xml:"enclosure"`
+
+	// iTunes and Podcasting 2.0 episode metadata.
+	Duration    string       `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Episode     string       `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Image       ITunesImage  `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	Transcripts []Transcript `xml:"https://podcastindex.org/namespace/1.0 transcript"`
+	Chapters    Chapters     `xml:"https://podcastindex.org/namespace/1.0 chapters"`
+
+	Media
+
+	// Base is the xml:base in scope for the item, if it differs from that
+	// of the channel. It may itself be relative to Channel.Base.
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+}
+
+// Enclosure is a media object attached to an item, typically a podcast
+// episode. Length is kept as text as publishers often leave it blank or fill
+// it with junk.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// Category is a topic that a channel or item is filed under. Domain, if set,
+// identifies the taxonomy that the name belongs to. Atom publishes this as the
+// scheme of a category.
+type Category struct {
+	Name   string `xml:",chardata"`
+	Domain string `xml:"domain,attr"`
+}
+
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type Transcript struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr"`
+	Rel      string `xml:"rel,attr"`
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return Feed{}, errors.Errorf("server responded with status %d", resp.StatusCode)
+type Chapters struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// GUID uniquely identifies an item within a feed. RSS publishes it as <guid>,
+// Atom as <id> and JSON Feed as the item id.
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+// PermaLink reports whether the GUID is also a URL pointing to the item. The
+// RSS specification makes this the default when the attribute is missing.
+func (g GUID) PermaLink() bool {
+	if strings.TrimSpace(g.Value) == "" {
+		return false
 	}
+	return !strings.EqualFold(strings.TrimSpace(g.IsPermaLink), "false")
+}
+
+// defaultFetcher is used by LoadURL.
+var defaultFetcher = NewFetcher(FetcherConfig{})
 
-	feed, err := Load(resp.Body)
+// LoadURL fetches and decodes the feed at url with no caching, using the
+// default fetcher configuration. Callers that need control over timeouts or
+// cancellation should use a Fetcher instead.
+func LoadURL(url string) (Feed, error) {
+	result, err := defaultFetcher.Fetch(context.Background(), url, Validators{})
 	if err != nil {
 		return Feed{}, err
 	}
-
-	return feed, nil
+	return result.Feed, nil
 }
 
 func LoadFile(file string) (Feed, error) {
@@ -67,23 +239,105 @@ func LoadFile(file string) (Feed, error) {
 	return Load(f)
 }
 
+// Load reads a feed document from r, detecting its format from the document
+// itself, and decodes it into a Feed.
 func Load(r io.Reader) (Feed, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Feed{}, err
+	}
+	return load(b, "")
+}
+
+// load converts b to UTF-8 and decodes it using the format implied by
+// contentType, falling back to sniffing the document when the content type is
+// missing or ambiguous. XML documents that fail to decode are passed through
+// Recover and decoded again, in which case the repairs that were needed are
+// recorded on the feed. If that fails too the original error is returned.
+func load(b []byte, contentType string) (Feed, error) {
+	b, err := toUTF8(b, contentType)
+	if err != nil {
+		return Feed{}, err
+	}
+
+	format := formatFromContentType(contentType)
+	feed, err := decodeFormat(b, format)
+	if err == nil {
+		feed.normalize()
+		return feed, nil
+	}
+	if format == FormatJSON {
+		return Feed{}, err
+	}
+
+	repaired, repairs := Recover(b)
+	if len(repairs) == 0 {
+		return Feed{}, err
+	}
+	feed, rerr := decodeFormat(repaired, format)
+	if rerr != nil {
+		return Feed{}, err
+	}
+	feed.normalize()
+	feed.Repairs = repairs
+	return feed, nil
+}
+
+// decodeFormat decodes b as format, sniffing the format if it is not known.
+func decodeFormat(b []byte, format Format) (Feed, error) {
+	if format == FormatUnknown {
+		var err error
+		if format, err = DetectFormat(b); err != nil {
+			return Feed{}, err
+		}
+	}
+
+	switch format {
+	case FormatAtom:
+		return loadAtom(b)
+	case FormatRDF:
+		return loadRDF(b)
+	case FormatJSON:
+		return loadJSON(b)
+	default:
+		return loadRSS(b)
+	}
+}
+
+func loadRSS(b []byte) (Feed, error) {
 	var feed Feed
-	dec := xml.NewDecoder(r)
-	dec.DefaultSpace = "Default"
-	if err := dec.Decode(&feed); err != nil {
+	if err := newDecoder(b).Decode(&feed); err != nil {
 		return Feed{}, err
 	}
+	feed.Format = FormatRSS
+	feed.Channel.rssAuthors()
+	feed.Channel.Base = inheritBase(feed.Base, feed.Channel.Base)
 
 	return feed, nil
 }
 
+func newDecoder(b []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.DefaultSpace = "Default"
+	return dec
+}
+
+// Quote quotes the text inside each startTag/endTag pair in input.
+//
+// Deprecated: Use NewQuoteReader, which streams the document and reports
+// unmatched tags as errors.
 func Quote(input, startTag, endTag string) string {
 	return ProcessElementText(input, startTag, endTag, strconv.Quote)
 }
 
 type StringModifierFunc func(input string) string
 
+// ProcessElementText replaces the text inside each startTag/endTag pair in
+// input with f(text). It returns an empty string if a start tag is not
+// matched by an end tag.
+//
+// Deprecated: Use NewElementTextReader, which streams the document, supports
+// several tag pairs at once and reports unmatched tags as errors.
 func ProcessElementText(input, startTag, endTag string, f StringModifierFunc) string {
 	var output strings.Builder
 
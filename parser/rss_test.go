@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -78,8 +79,13 @@ func TestLoad(t *testing.T) {
 		err         bool
 	}{
 		{
-			name: "invalid",
-			err:  true,
+			// invalid is truncated after the channel image and is
+			// recovered by closing the open elements.
+			name:        "invalid",
+			title:       "The Checkly Blog",
+			description: "The Checkly Blog is your go-to place for technical stories on building a SaaS, building a company and growing it from scratch.",
+			image:       "https://blog.checklyhq.com/favicon.png",
+			link:        "https://blog.checklyhq.com/",
 		},
 		{
 			name:        "checklymin",
@@ -151,6 +157,184 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestGUID(t *testing.T) {
+	feed, err := LoadFile(filepath.Join("..", "testdata", "checkly.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Channel.Items) == 0 {
+		t.Fatalf("expected items, got none")
+	}
+
+	guid := feed.Channel.Items[0].GUID
+	if want := "5ca7cf3db8aecf00cc47c960"; guid.Value != want {
+		t.Errorf("expected guid %q, got %q", want, guid.Value)
+	}
+	if guid.PermaLink() {
+		t.Errorf("expected guid not to be a permalink")
+	}
+
+	testcases := []struct {
+		name string
+		guid GUID
+		want bool
+	}{
+		{name: "default", guid: GUID{Value: "http://example.com/1"}, want: true},
+		{name: "explicit true", guid: GUID{Value: "http://example.com/1", IsPermaLink: "true"}, want: true},
+		{name: "explicit false", guid: GUID{Value: "abc", IsPermaLink: "false"}, want: false},
+		{name: "empty", guid: GUID{}, want: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.guid.PermaLink(); got != tc.want {
+				t.Errorf("wanted %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSelfLink(t *testing.T) {
+	testcases := []struct {
+		file string
+		want string
+	}{
+		{file: "checkly.xml", want: "https://blog.checklyhq.com/rss/"},
+		{file: "atom.xml", want: "https://example.org/feed.atom"},
+		{file: "hackernews.xml", want: ""},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.file, func(t *testing.T) {
+			feed, err := LoadFile(filepath.Join("..", "testdata", tc.file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := feed.Channel.SelfLink(); got != tc.want {
+				t.Errorf("expected self link %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   string
+		channel []Category
+		item    []Category
+	}{
+		{
+			name:    "rss",
+			input:   `<rss version="2.0"><channel><category domain="http://example.com/tax">News</category><item><category>Go</category><category domain="http://example.com/tax"> Open  Source </category></item></channel></rss>`,
+			channel: []Category{{Name: "News", Domain: "http://example.com/tax"}},
+			item:    []Category{{Name: "Go"}, {Name: "Open Source", Domain: "http://example.com/tax"}},
+		},
+		{
+			name:    "atom",
+			input:   `<feed xmlns="http://www.w3.org/2005/Atom"><category term="news" scheme="http://example.com/tax" label="News"/><entry><category term="go"/></entry></feed>`,
+			channel: []Category{{Name: "news", Domain: "http://example.com/tax"}},
+			item:    []Category{{Name: "go"}},
+		},
+		{
+			name:  "json",
+			input: `{"version": "https://jsonfeed.org/version/1.1", "title": "T", "items": [{"id": "1", "tags": ["go", "open source"]}]}`,
+			item:  []Category{{Name: "go"}, {Name: "open source"}},
+		},
+		{
+			name:    "rdf",
+			input:   `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><dc:subject>News</dc:subject></channel><item><dc:subject>Go</dc:subject></item></rdf:RDF>`,
+			channel: []Category{{Name: "News"}},
+			item:    []Category{{Name: "Go"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(feed.Channel.Categories, tc.channel) {
+				t.Errorf("expected channel categories %v, got %v", tc.channel, feed.Channel.Categories)
+			}
+			if len(feed.Channel.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(feed.Channel.Items))
+			}
+			if got := feed.Channel.Items[0].Categories; !reflect.DeepEqual(got, tc.item) {
+				t.Errorf("expected item categories %v, got %v", tc.item, got)
+			}
+		})
+	}
+}
+
+func TestPublishingHints(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  Channel
+	}{
+		{
+			name:  "rss",
+			input: `<rss version="2.0"><channel><ttl>60</ttl><skipHours><hour>1</hour><hour>2</hour></skipHours><skipDays><day>Sunday</day></skipDays></channel></rss>`,
+			want:  Channel{TTL: "60", SkipHours: []string{"1", "2"}, SkipDays: []string{"Sunday"}},
+		},
+		{
+			name:  "atom",
+			input: `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><sy:updatePeriod>hourly</sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency></feed>`,
+			want:  Channel{UpdatePeriod: "hourly", UpdateFrequency: "2"},
+		},
+		{
+			name:  "rdf",
+			input: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel><sy:updatePeriod> daily </sy:updatePeriod></channel></rdf:RDF>`,
+			want:  Channel{UpdatePeriod: "daily"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := feed.Channel
+			if c.TTL != tc.want.TTL {
+				t.Errorf("expected ttl %q, got %q", tc.want.TTL, c.TTL)
+			}
+			if !reflect.DeepEqual(c.SkipHours, tc.want.SkipHours) {
+				t.Errorf("expected skip hours %q, got %q", tc.want.SkipHours, c.SkipHours)
+			}
+			if !reflect.DeepEqual(c.SkipDays, tc.want.SkipDays) {
+				t.Errorf("expected skip days %q, got %q", tc.want.SkipDays, c.SkipDays)
+			}
+			if c.UpdatePeriod != tc.want.UpdatePeriod {
+				t.Errorf("expected update period %q, got %q", tc.want.UpdatePeriod, c.UpdatePeriod)
+			}
+			if c.UpdateFrequency != tc.want.UpdateFrequency {
+				t.Errorf("expected update frequency %q, got %q", tc.want.UpdateFrequency, c.UpdateFrequency)
+			}
+		})
+	}
+}
+
+func TestItemContent(t *testing.T) {
+	feed, err := LoadFile(filepath.Join("..", "testdata", "checkly.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Channel.Items) == 0 {
+		t.Fatalf("expected items, got none")
+	}
+
+	item := feed.Channel.Items[0]
+	wantDescription := "This is how we configured Stripe Billing for our SaaS and it hooked it up to our backend. We only use one webhook to keep all things running smoothly."
+	if got := strings.TrimSpace(item.Description); got != wantDescription {
+		t.Errorf("expected description %q, got %q", wantDescription, got)
+	}
+	wantContent := `<img src="https://blog.checklyhq.com/content/images/2019/04/money-shower-yoshitora-japanese-woodblock_1_614241d00d1c50a81398a3028fec37b1.jpg"`
+	if got := strings.TrimSpace(item.Content); !strings.HasPrefix(got, wantContent) {
+		t.Errorf("expected content to start with %q, got %q", wantContent, got)
+	}
+}
+
 func TestRepositoryURL(t *testing.T) {
 	testcases := []struct {
 		name        string
@@ -212,3 +396,50 @@ func TestRepositoryURL(t *testing.T) {
 		})
 	}
 }
+
+func TestCloud(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  Cloud
+		url   string
+	}{
+		{
+			name:  "http-post",
+			input: `<rss version="2.0"><channel><cloud domain="rpc.example.com" port="5337" path="/rsscloud/pleaseNotify" registerProcedure="" protocol="http-post"/></channel></rss>`,
+			want:  Cloud{Domain: "rpc.example.com", Port: "5337", Path: "/rsscloud/pleaseNotify", Protocol: "http-post"},
+			url:   "http://rpc.example.com:5337/rsscloud/pleaseNotify",
+		},
+		{
+			name:  "default port",
+			input: `<rss version="2.0"><channel><cloud domain="rpc.example.com" path="notify" protocol="HTTP-POST"/></channel></rss>`,
+			want:  Cloud{Domain: "rpc.example.com", Path: "notify", Protocol: "HTTP-POST"},
+			url:   "http://rpc.example.com:80/notify",
+		},
+		{
+			name:  "xml-rpc",
+			input: `<rss version="2.0"><channel><cloud domain="rpc.sys.com" port="80" path="/RPC2" registerProcedure="myCloud.rssPleaseNotify" protocol="xml-rpc"/></channel></rss>`,
+			want:  Cloud{Domain: "rpc.sys.com", Port: "80", Path: "/RPC2", RegisterProcedure: "myCloud.rssPleaseNotify", Protocol: "xml-rpc"},
+		},
+		{
+			name:  "none",
+			input: `<rss version="2.0"><channel></channel></rss>`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			c := feed.Channel
+			if c.Cloud != tc.want {
+				t.Errorf("expected cloud %+v, got %+v", tc.want, c.Cloud)
+			}
+			if got := c.Cloud.RegisterURL(); got != tc.url {
+				t.Errorf("expected register url %q, got %q", tc.url, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxElementBytes is the default limit on the size of the text inside
+// a single element rewritten by an ElementTextReader.
+const DefaultMaxElementBytes = 16 << 20
+
+var (
+	// ErrUnmatchedTag is returned by an ElementTextReader when the input ends
+	// inside an element whose text is being rewritten.
+	ErrUnmatchedTag = errors.New("unmatched tag")
+
+	// ErrElementTooLarge is returned by an ElementTextReader when the text
+	// of an element is longer than MaxElementBytes.
+	ErrElementTooLarge = errors.New("element text too large")
+)
+
+// TagPair is a start and end tag whose enclosed text is rewritten by an
+// ElementTextReader.
+type TagPair struct {
+	Start string
+	End   string
+}
+
+// ContentEncoded is the pair of tags around the full content of an RSS item.
+var ContentEncoded = TagPair{Start: TagStartContentEncoded, End: TagEndContentEncoded}
+
+// ElementTextReader streams a document, replacing the text between each pair
+// of tags with the result of passing it through a StringModifierFunc. Only the
+// text of the element currently being rewritten is held in memory, so
+// documents of any size can be transformed as long as their individual
+// elements are no larger than MaxElementBytes.
+type ElementTextReader struct {
+	// MaxElementBytes limits the size of the text of a single element. It
+	// may be changed before the first call to Read.
+	MaxElementBytes int
+
+	src   io.Reader
+	f     StringModifierFunc
+	pairs []TagPair
+
+	// pending holds input that has been read from src but not yet
+	// processed, and out holds processed output that has not yet been
+	// returned from Read. pending is a slice of buf.
+	pending []byte
+	buf     []byte
+	out     bytes.Buffer
+	chunk   []byte
+
+	// inside is the index of the pair whose element we are in, or -1.
+	// scanned is how much of pending is known not to contain its end tag.
+	inside  int
+	scanned int
+	eof     bool
+	err     error
+}
+
+// NewElementTextReader returns a reader that yields the contents of r with the
+// text inside each of the given tag pairs replaced by f(text). Tags are
+// matched literally and elements do not nest: the first end tag after a start
+// tag ends the element.
+func NewElementTextReader(r io.Reader, f StringModifierFunc, pairs ...TagPair) *ElementTextReader {
+	return &ElementTextReader{
+		MaxElementBytes: DefaultMaxElementBytes,
+		src:             r,
+		f:               f,
+		pairs:           pairs,
+		chunk:           make([]byte, 32<<10),
+		inside:          -1,
+	}
+}
+
+// NewQuoteReader returns a reader that yields the contents of r with the text
+// inside each of the given tag pairs quoted as a Go string literal.
+func NewQuoteReader(r io.Reader, pairs ...TagPair) *ElementTextReader {
+	return NewElementTextReader(r, strconv.Quote, pairs...)
+}
+
+func (t *ElementTextReader) Read(p []byte) (int, error) {
+	for t.out.Len() == 0 && t.err == nil {
+		if !t.eof {
+			// Move what is left to the front so that the buffer
+			// only grows when an element needs it to.
+			t.pending = append(t.buf[:0], t.pending...)
+			n, err := t.src.Read(t.chunk)
+			t.pending = append(t.pending, t.chunk[:n]...)
+			t.buf = t.pending
+			if err == io.EOF {
+				t.eof = true
+			} else if err != nil {
+				t.err = err
+			}
+		}
+		if err := t.process(); err != nil {
+			t.err = err
+		}
+		if t.eof && t.err == nil {
+			t.err = t.finish()
+		}
+	}
+
+	if t.out.Len() > 0 {
+		return t.out.Read(p)
+	}
+	return 0, t.err
+}
+
+// process moves as much of pending to out as can be done without knowing
+// what comes next.
+func (t *ElementTextReader) process() error {
+	for {
+		if t.inside == -1 {
+			i, pair := t.nextStart()
+			if pair == -1 {
+				// Hold back anything that may be the start of a
+				// tag which continues in the next chunk.
+				keep := t.partialStart()
+				t.out.Write(t.pending[:len(t.pending)-keep])
+				t.pending = t.pending[len(t.pending)-keep:]
+				return nil
+			}
+			end := i + len(t.pairs[pair].Start)
+			t.out.Write(t.pending[:end])
+			t.pending = t.pending[end:]
+			t.inside = pair
+			t.scanned = 0
+			continue
+		}
+
+		end := t.pairs[t.inside].End
+		j := bytes.Index(t.pending[t.scanned:], []byte(end))
+		if j == -1 {
+			if len(t.pending) > t.MaxElementBytes+len(end) {
+				return errors.Wrapf(ErrElementTooLarge, "%s", t.pairs[t.inside].Start)
+			}
+			if t.scanned = len(t.pending) - len(end) + 1; t.scanned < 0 {
+				t.scanned = 0
+			}
+			return nil
+		}
+		j += t.scanned
+		t.out.WriteString(t.f(string(t.pending[:j])))
+		t.out.WriteString(end)
+		t.pending = t.pending[j+len(end):]
+		t.inside = -1
+	}
+}
+
+// finish flushes what is left once the input has been exhausted.
+func (t *ElementTextReader) finish() error {
+	if t.inside != -1 {
+		return errors.Wrapf(ErrUnmatchedTag, "%s has no matching %s", t.pairs[t.inside].Start, t.pairs[t.inside].End)
+	}
+	t.out.Write(t.pending)
+	t.pending = nil
+	return io.EOF
+}
+
+// nextStart returns the position of the earliest start tag in pending and the
+// index of its pair, or -1 if there is none.
+func (t *ElementTextReader) nextStart() (int, int) {
+	first, pair := -1, -1
+	for k, p := range t.pairs {
+		i := bytes.Index(t.pending, []byte(p.Start))
+		if i != -1 && (first == -1 || i < first) {
+			first, pair = i, k
+		}
+	}
+	return first, pair
+}
+
+// partialStart returns the length of the longest suffix of pending that is a
+// prefix of one of the start tags. At end of input there is nothing to wait
+// for.
+func (t *ElementTextReader) partialStart() int {
+	if t.eof {
+		return 0
+	}
+	longest := 0
+	for _, p := range t.pairs {
+		for n := len(p.Start) - 1; n > longest; n-- {
+			if bytes.HasSuffix(t.pending, []byte(p.Start[:n])) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestElementTextReader(t *testing.T) {
+	mod := func(_ string) string {
+		return "the replacement text"
+	}
+	title := TagPair{Start: "<title>", End: "</title>"}
+	testcases := []struct {
+		name  string
+		input string
+		pairs []TagPair
+		want  string
+		err   error
+	}{
+		{
+			name:  "missing end tag",
+			input: `preamble <content:encoded>some stuff`,
+			pairs: []TagPair{ContentEncoded},
+			err:   ErrUnmatchedTag,
+		},
+		{
+			name:  "no tag pairs",
+			input: `some bare text`,
+			pairs: []TagPair{ContentEncoded},
+			want:  `some bare text`,
+		},
+		{
+			name:  "single tag pair",
+			input: `some preamble text <content:encoded>some stuff that needs quoting</content:encoded> some postamble text`,
+			pairs: []TagPair{ContentEncoded},
+			want:  `some preamble text <content:encoded>the replacement text</content:encoded> some postamble text`,
+		},
+		{
+			name:  "multiple tag pairs",
+			input: `some preamble text <content:encoded>some stuff that needs quoting</content:encoded> some <content:encoded>some stuff that needs quoting</content:encoded> postamble text`,
+			pairs: []TagPair{ContentEncoded},
+			want:  `some preamble text <content:encoded>the replacement text</content:encoded> some <content:encoded>the replacement text</content:encoded> postamble text`,
+		},
+		{
+			name:  "different tag pairs",
+			input: `<title>a title</title><content:encoded>some content</content:encoded><title>another</title>`,
+			pairs: []TagPair{ContentEncoded, title},
+			want:  `<title>the replacement text</title><content:encoded>the replacement text</content:encoded><title>the replacement text</title>`,
+		},
+		{
+			name:  "partial start tag at end",
+			input: `some text <content:enc`,
+			pairs: []TagPair{ContentEncoded},
+			want:  `some text <content:enc`,
+		},
+		{
+			name:  "empty element",
+			input: `<content:encoded></content:encoded>`,
+			pairs: []TagPair{ContentEncoded},
+			want:  `<content:encoded>the replacement text</content:encoded>`,
+		},
+	}
+
+	readers := []struct {
+		name string
+		wrap func(r io.Reader) io.Reader
+	}{
+		{name: "whole", wrap: func(r io.Reader) io.Reader { return r }},
+		{name: "one byte", wrap: iotest.OneByteReader},
+		{name: "half", wrap: iotest.HalfReader},
+	}
+
+	for _, tc := range testcases {
+		for _, rd := range readers {
+			t.Run(tc.name+"/"+rd.name, func(t *testing.T) {
+				r := NewElementTextReader(rd.wrap(strings.NewReader(tc.input)), mod, tc.pairs...)
+				got, err := ioutil.ReadAll(iotest.OneByteReader(r))
+				if tc.err != nil {
+					if err == nil || !strings.Contains(err.Error(), tc.err.Error()) {
+						t.Fatalf("expected error %v, got %v", tc.err, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(got) != tc.want {
+					t.Errorf("wanted %q, got %q", tc.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestElementTextReaderTooLarge(t *testing.T) {
+	input := "<content:encoded>" + strings.Repeat("x", 100) + "</content:encoded>"
+	r := NewElementTextReader(strings.NewReader(input), strings.ToUpper, ContentEncoded)
+	r.MaxElementBytes = 10
+	r.chunk = make([]byte, 16)
+	if _, err := ioutil.ReadAll(r); err == nil || !strings.Contains(err.Error(), ErrElementTooLarge.Error()) {
+		t.Fatalf("expected error %v, got %v", ErrElementTooLarge, err)
+	}
+}
+
+func TestQuoteReader(t *testing.T) {
+	input, err := ioutil.ReadFile(filepath.Join("..", "testdata", "checkly.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Quote(string(input), TagStartContentEncoded, TagEndContentEncoded)
+	got, err := ioutil.ReadAll(NewQuoteReader(iotest.HalfReader(bytes.NewReader(input)), ContentEncoded))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("expected streamed output to match Quote")
+	}
+}
+
+// largeFeed returns a document of roughly the size of a large podcast feed,
+// with full content in every item.
+func largeFeed(b *testing.B) []byte {
+	item, err := ioutil.ReadFile(filepath.Join("..", "testdata", "checkly.xml"))
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	for buf.Len() < 8<<20 {
+		buf.Write(item)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkProcessElementText(b *testing.B) {
+	input := string(largeFeed(b))
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Quote(input, TagStartContentEncoded, TagEndContentEncoded)
+	}
+}
+
+func BenchmarkElementTextReader(b *testing.B) {
+	input := largeFeed(b)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewQuoteReader(bytes.NewReader(input), ContentEncoded)
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package rss
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss/parser"
+)
+
+// Enclosure is a media file attached to an item, most commonly the audio for a
+// podcast episode.
+type Enclosure struct {
+	ID     int64  `db:"id" json:"id"`
+	ItemID int64  `db:"item_id" json:"itemID"`
+	URL    string `db:"url" json:"url"`
+	Type   string `db:"type" json:"type"`
+	Length int64  `db:"length" json:"length"`
+}
+
+// Podcast holds the iTunes and Podcasting 2.0 metadata published for an
+// episode. Duration is in seconds. Either is 0 if it is unknown.
+type Podcast struct {
+	Duration       int64  `db:"duration" json:"duration"`
+	Episode        int    `db:"episode" json:"episode"`
+	Image          string `db:"episode_image" json:"image"`
+	TranscriptURL  string `db:"transcript_url" json:"transcriptURL"`
+	TranscriptType string `db:"transcript_type" json:"transcriptType"`
+	ChaptersURL    string `db:"chapters_url" json:"chaptersURL"`
+}
+
+func newEnclosures(in []parser.Enclosure, base string) []*Enclosure {
+	var enclosures []*Enclosure
+	seen := make(map[string]bool)
+	for _, e := range in {
+		url := parser.ResolveURL(base, e.URL)
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		length, _ := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+		if length < 0 {
+			length = 0
+		}
+		enclosures = append(enclosures, &Enclosure{
+			URL:    url,
+			Type:   strings.TrimSpace(e.Type),
+			Length: length,
+		})
+	}
+	return enclosures
+}
+
+func newPodcast(item parser.Item, base string) Podcast {
+	var p Podcast
+	if d, err := parseDuration(item.Duration); err == nil {
+		p.Duration = d
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(item.Episode)); err == nil && n > 0 && n <= math.MaxInt32 {
+		p.Episode = n
+	}
+	p.Image = parser.ResolveURL(base, item.Image.Href)
+	for _, t := range item.Transcripts {
+		if url := parser.ResolveURL(base, t.URL); url != "" {
+			p.TranscriptURL = url
+			p.TranscriptType = strings.TrimSpace(t.Type)
+			break
+		}
+	}
+	p.ChaptersURL = parser.ResolveURL(base, item.Chapters.URL)
+	return p
+}
+
+// maxDuration bounds the durations we accept, in seconds. Nothing published
+// runs for anywhere near this long, so longer values are nonsense.
+const maxDuration = math.MaxInt32
+
+// parseDuration parses an itunes:duration value, which may be a number of
+// seconds or a colon separated "H:MM:SS" or "MM:SS" value, into seconds.
+func parseDuration(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty duration")
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, errors.Errorf("invalid duration %q", s)
+	}
+	var seconds float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+			return 0, errors.Errorf("invalid duration %q", s)
+		}
+		seconds = seconds*60 + n
+	}
+	if seconds > maxDuration {
+		return 0, errors.Errorf("duration %q is too long", s)
+	}
+	return int64(seconds), nil
+}
@@ -0,0 +1,111 @@
+package rss_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromChannelPodcast(t *testing.T) {
+	xmlFeed, err := parser.LoadFile(filepath.Join("testdata", "podcast.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	feed, err := rss.NewFromChannel(xmlFeed.Channel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(feed.Items))
+	}
+
+	item := feed.Items[0]
+	if want := "Episode 2: Enclosures"; item.Title != want {
+		t.Errorf("expected title %q, got %q", want, item.Title)
+	}
+	if len(item.Enclosures) != 1 {
+		t.Fatalf("expected 1 enclosure, got %d", len(item.Enclosures))
+	}
+	enclosure := item.Enclosures[0]
+	if want := "https://cdn.podcast.example.com/episode-2.mp3"; enclosure.URL != want {
+		t.Errorf("expected enclosure url %q, got %q", want, enclosure.URL)
+	}
+	if want := "audio/mpeg"; enclosure.Type != want {
+		t.Errorf("expected enclosure type %q, got %q", want, enclosure.Type)
+	}
+	if want := int64(24986239); enclosure.Length != want {
+		t.Errorf("expected enclosure length %d, got %d", want, enclosure.Length)
+	}
+
+	if want := int64(3723); item.Duration != want {
+		t.Errorf("expected duration %d, got %d", want, item.Duration)
+	}
+	if want := 2; item.Episode != want {
+		t.Errorf("expected episode %d, got %d", want, item.Episode)
+	}
+	if want := "https://podcast.example.com/episode-2.jpg"; item.Podcast.Image != want {
+		t.Errorf("expected image %q, got %q", want, item.Podcast.Image)
+	}
+	if want := "https://podcast.example.com/episode-2.vtt"; item.TranscriptURL != want {
+		t.Errorf("expected transcript %q, got %q", want, item.TranscriptURL)
+	}
+	if want := "https://podcast.example.com/episode-2.json"; item.ChaptersURL != want {
+		t.Errorf("expected chapters %q, got %q", want, item.ChaptersURL)
+	}
+
+	item = feed.Items[1]
+	if want := int64(1845); item.Duration != want {
+		t.Errorf("expected duration %d, got %d", want, item.Duration)
+	}
+	if len(item.Enclosures) != 1 || item.Enclosures[0].Length != 0 {
+		t.Errorf("expected a single enclosure with unknown length, got %+v", item.Enclosures)
+	}
+}
+
+func TestNewFromChannelPodcastLimits(t *testing.T) {
+	testcases := []struct {
+		name     string
+		duration string
+		episode  string
+		want     int64
+		episodes int
+	}{
+		{"valid", "1:02:03", "7", 3723, 7},
+		{"infinite", "Inf", "", 0, 0},
+		{"not a number", "NaN", "", 0, 0},
+		{"exponent", "1e12", "", 0, 0},
+		{"huge hours", "99999999999:00:00", "", 0, 0},
+		{"huge episode", "", "4294967296", 0, 0},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := rss.NewFromChannel(parser.Channel{
+				Title:       "Podcast",
+				Description: "D",
+				Link:        "https://podcast.example.com/",
+				Items: []parser.Item{{
+					Title:           "Episode",
+					Link:            "https://podcast.example.com/episode",
+					PublicationDate: "Mon, 01 Apr 2019 10:00:00 GMT",
+					Duration:        tc.duration,
+					Episode:         tc.episode,
+				}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(feed.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(feed.Items))
+			}
+			if got := feed.Items[0].Duration; got != tc.want {
+				t.Errorf("expected duration %d, got %d", tc.want, got)
+			}
+			if got := feed.Items[0].Episode; got != tc.episodes {
+				t.Errorf("expected episode %d, got %d", tc.episodes, got)
+			}
+		})
+	}
+}
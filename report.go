@@ -0,0 +1,203 @@
+package rss
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss/parser"
+)
+
+// Severity is how serious a validation issue is. Errors cost us content: the
+// item they were found in is left out of the feed. Warnings are departures
+// from the specification that we were able to work around.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found while converting a feed. Item is the position of
+// the item it concerns in the document, counting from one, or zero if it
+// concerns the channel or the document as a whole. Field is the name of the
+// element at fault in the feed's own format, Value is what the feed had there,
+// and Spec links to the part of the specification that was broken.
+type Issue struct {
+	Item     int      `json:"item,omitempty"`
+	Field    string   `json:"field"`
+	Value    string   `json:"value,omitempty"`
+	Reason   string   `json:"reason"`
+	Severity Severity `json:"severity"`
+	Spec     string   `json:"spec,omitempty"`
+}
+
+// Report lists the issues found when a feed was last fetched, in the spirit of
+// the W3C feed validator.
+type Report struct {
+	CheckedAt time.Time     `json:"checkedAt"`
+	Format    parser.Format `json:"format"`
+	Issues    []Issue       `json:"issues"`
+}
+
+// Count returns the number of issues of the given severity.
+func (r Report) Count(severity Severity) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// Value stores a Report as JSON so that it can be kept in a single column.
+func (r Report) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan loads a Report stored by Value.
+func (r *Report) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*r = Report{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	default:
+		return errors.Errorf("can not scan %T into Report", src)
+	}
+}
+
+// The fields that issues are reported against, before they are translated to
+// the element names of a particular format by specs.
+const (
+	fieldDocument    = "document"
+	fieldDescription = "description"
+	fieldTitle       = "title"
+	fieldLink        = "link"
+	fieldDate        = "date"
+	fieldGUID        = "guid"
+)
+
+// spec names the element that holds a field in a format and the section of the
+// format's specification that describes it.
+type spec struct {
+	element string
+	url     string
+}
+
+var specs = map[parser.Format]map[string]spec{
+	parser.FormatRSS: {
+		fieldDescription: {"description", "https://www.rssboard.org/rss-specification#requiredChannelElements"},
+		fieldTitle:       {"title", "https://www.rssboard.org/rss-specification#hrelementsOfLtitemgt"},
+		fieldLink:        {"link", "https://www.rssboard.org/rss-specification#hrelementsOfLtitemgt"},
+		fieldDate:        {"pubDate", "https://www.rssboard.org/rss-specification#ltpubdategtSubelementOfLtitemgt"},
+		fieldGUID:        {"guid", "https://www.rssboard.org/rss-specification#ltguidgtSubelementOfLtitemgt"},
+	},
+	parser.FormatAtom: {
+		fieldTitle: {"title", "https://www.rfc-editor.org/rfc/rfc4287#section-4.2.14"},
+		fieldLink:  {"link", "https://www.rfc-editor.org/rfc/rfc4287#section-4.2.7"},
+		fieldDate:  {"published", "https://www.rfc-editor.org/rfc/rfc4287#section-3.3"},
+		fieldGUID:  {"id", "https://www.rfc-editor.org/rfc/rfc4287#section-4.2.6"},
+	},
+	parser.FormatRDF: {
+		fieldDescription: {"description", "https://web.resource.org/rss/1.0/spec#s5.3.3"},
+		fieldTitle:       {"title", "https://web.resource.org/rss/1.0/spec#s5.5.1"},
+		fieldLink:        {"link", "https://web.resource.org/rss/1.0/spec#s5.5.2"},
+		fieldDate:        {"dc:date", "https://web.resource.org/rss/1.0/modules/dc/"},
+		fieldGUID:        {"rdf:about", "https://web.resource.org/rss/1.0/spec#s5.5"},
+	},
+	parser.FormatJSON: {
+		fieldTitle: {"title", "https://www.jsonfeed.org/version/1.1/#items-a-name-items-a"},
+		fieldLink:  {"url", "https://www.jsonfeed.org/version/1.1/#items-a-name-items-a"},
+		fieldDate:  {"date_published", "https://www.jsonfeed.org/version/1.1/#items-a-name-items-a"},
+		fieldGUID:  {"id", "https://www.jsonfeed.org/version/1.1/#items-a-name-items-a"},
+	},
+}
+
+// wellFormedSpec is the reference for documents that had to be repaired
+// before they could be parsed. JSON feeds are never repaired.
+const wellFormedSpec = "https://www.w3.org/TR/xml/#sec-well-formed"
+
+// dateLayouts are the layouts, as returned by parser.ParseDate, that each
+// format's specification allows. Dates in any other layout are understood but
+// reported.
+var dateLayouts = map[parser.Format][]string{
+	parser.FormatRSS: withWeekday(
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04 -0700",
+		"2 Jan 06 15:04:05 -0700",
+		"2 Jan 06 15:04 -0700",
+		"2 Jan 2006 15:04:05 MST",
+		"2 Jan 2006 15:04 MST",
+		"2 Jan 06 15:04:05 MST",
+		"2 Jan 06 15:04 MST",
+	),
+	parser.FormatAtom: {time.RFC3339Nano},
+	parser.FormatRDF:  {time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02", "2006-01", "2006"},
+	parser.FormatJSON: {time.RFC3339Nano},
+}
+
+// add records an issue with a field of the item at position item. Feeds whose
+// format is not known are reported as if they were RSS.
+func (r *Report) add(item int, field string, severity Severity, value, reason string, args ...interface{}) {
+	format := r.Format
+	if _, ok := specs[format]; !ok {
+		format = parser.FormatRSS
+	}
+	s, ok := specs[format][field]
+	if !ok {
+		s = spec{element: field}
+	}
+	r.Issues = append(r.Issues, Issue{
+		Item:     item,
+		Field:    s.element,
+		Value:    value,
+		Reason:   fmt.Sprintf(reason, args...),
+		Severity: severity,
+		Spec:     s.url,
+	})
+}
+
+// addRepairs records a warning for each kind of repair the parser had to make
+// to the document.
+func (r *Report) addRepairs(repairs []parser.Repair) {
+	for _, repair := range repairs {
+		r.Issues = append(r.Issues, Issue{
+			Field:    fieldDocument,
+			Reason:   fmt.Sprintf("document is not well-formed: repaired %s", repair),
+			Severity: SeverityWarning,
+			Spec:     wellFormedSpec,
+		})
+	}
+}
+
+// withWeekday returns layouts along with each of them preceded by the optional
+// RFC 822 weekday.
+func withWeekday(layouts ...string) []string {
+	for _, layout := range layouts {
+		layouts = append(layouts, "Mon, "+layout)
+	}
+	return layouts
+}
+
+// checkDateLayout warns about a date that was understood, but is not written
+// the way the feed's format requires.
+func (r *Report) checkDateLayout(item int, value, layout string) {
+	allowed, ok := dateLayouts[r.Format]
+	if !ok {
+		return
+	}
+	for _, l := range allowed {
+		if l == layout {
+			return
+		}
+	}
+	r.add(item, fieldDate, SeverityWarning, value, "date is not in the format the specification requires")
+}
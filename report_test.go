@@ -0,0 +1,113 @@
+package rss_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromFeedReport(t *testing.T) {
+	testcases := []struct {
+		name   string
+		input  string
+		items  int
+		issues []rss.Issue
+	}{
+		{
+			name:   "valid rss",
+			input:  `<rss version="2.0"><channel><title>T</title><description>D</description><link>https://example.com/</link><item><title>A</title><link>/a</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item></channel></rss>`,
+			items:  1,
+			issues: []rss.Issue{},
+		},
+		{
+			name: "skipped rss items",
+			input: `<rss version="2.0"><channel><title>T</title><link>https://example.com/</link>` +
+				`<item><title>No date</title><link>/a</link></item>` +
+				`<item><title>Bad date</title><link>/b</link><pubDate>yesterday</pubDate></item>` +
+				`<item><link>/c</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item>` +
+				`<item><title>ISO date</title><link>/d</link><guid>d</guid><pubDate>2019-04-01T10:00:00Z</pubDate></item>` +
+				`<item><title>Repeat</title><link>/e</link><guid>d</guid><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item>` +
+				`</channel></rss>`,
+			items: 2,
+			issues: []rss.Issue{
+				{Item: 1, Field: "pubDate", Reason: "item has no publication date and was skipped", Severity: rss.SeverityError, Spec: "https://www.rssboard.org/rss-specification#ltpubdategtSubelementOfLtitemgt"},
+				{Item: 2, Field: "pubDate", Value: "yesterday", Reason: "publication date could not be understood and the item was skipped", Severity: rss.SeverityError, Spec: "https://www.rssboard.org/rss-specification#ltpubdategtSubelementOfLtitemgt"},
+				{Item: 3, Field: "title", Reason: "item was skipped: title is required", Severity: rss.SeverityError, Spec: "https://www.rssboard.org/rss-specification#hrelementsOfLtitemgt"},
+				{Item: 4, Field: "pubDate", Value: "2019-04-01T10:00:00Z", Reason: "date is not in the format the specification requires", Severity: rss.SeverityWarning, Spec: "https://www.rssboard.org/rss-specification#ltpubdategtSubelementOfLtitemgt"},
+				{Item: 5, Field: "guid", Value: "d", Reason: "item has the same identifier as item 4 and will replace it", Severity: rss.SeverityWarning, Spec: "https://www.rssboard.org/rss-specification#ltguidgtSubelementOfLtitemgt"},
+				{Field: "description", Reason: "channel has no description", Severity: rss.SeverityWarning, Spec: "https://www.rssboard.org/rss-specification#requiredChannelElements"},
+			},
+		},
+		{
+			name: "rss dates",
+			input: `<rss version="2.0"><channel><title>T</title><description>D</description><link>https://example.com/</link>` +
+				`<item><title>Unknown zone</title><link>/a</link><pubDate>Mon, 01 Apr 2019 10:00:00 XYZT</pubDate></item>` +
+				`<item><title>Long names</title><link>/b</link><pubDate>Monday, 1 April 2019 10:00:00 GMT</pubDate></item>` +
+				`<item><title>Named zone</title><link>/c</link><pubDate>Mon, 01 Apr 2019 06:00:00 EDT</pubDate></item>` +
+				`</channel></rss>`,
+			items: 3,
+			issues: []rss.Issue{
+				{Item: 1, Field: "pubDate", Value: "Mon, 01 Apr 2019 10:00:00 XYZT", Reason: "time zone is not known, so the date was read as UTC", Severity: rss.SeverityWarning, Spec: "https://www.rssboard.org/rss-specification#ltpubdategtSubelementOfLtitemgt"},
+				{Item: 2, Field: "pubDate", Value: "Monday, 1 April 2019 10:00:00 GMT", Reason: "date is not in the format the specification requires", Severity: rss.SeverityWarning, Spec: "https://www.rssboard.org/rss-specification#ltpubdategtSubelementOfLtitemgt"},
+			},
+		},
+		{
+			name:  "atom",
+			input: `<feed xmlns="http://www.w3.org/2005/Atom"><title>T</title><link href="https://example.com/"/><entry><title>A</title><updated>Mon, 01 Apr 2019 10:00:00 GMT</updated></entry></feed>`,
+			issues: []rss.Issue{
+				{Item: 1, Field: "published", Value: "Mon, 01 Apr 2019 10:00:00 GMT", Reason: "date is not in the format the specification requires", Severity: rss.SeverityWarning, Spec: "https://www.rfc-editor.org/rfc/rfc4287#section-3.3"},
+				{Item: 1, Field: "link", Reason: "item was skipped: link is required", Severity: rss.SeverityError, Spec: "https://www.rfc-editor.org/rfc/rfc4287#section-4.2.7"},
+			},
+		},
+		{
+			name: "json without dates",
+			input: `{"version":"https://jsonfeed.org/version/1.1","title":"T","home_page_url":"https://example.com/","items":[` +
+				`{"id":"a","title":"No date","url":"https://example.com/a"},` +
+				`{"id":"b","title":"Bad date","url":"https://example.com/b","date_published":"yesterday"}]}`,
+			items: 2,
+			issues: []rss.Issue{
+				{Item: 2, Field: "date_published", Value: "yesterday", Reason: "publication date could not be understood and was ignored", Severity: rss.SeverityWarning, Spec: "https://www.jsonfeed.org/version/1.1/#items-a-name-items-a"},
+			},
+		},
+		{
+			name: "rdf without dates",
+			input: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/">` +
+				`<channel rdf:about="https://example.com/"><title>T</title><link>https://example.com/</link><description>D</description></channel>` +
+				`<item rdf:about="https://example.com/a"><title>No date</title><link>https://example.com/a</link></item></rdf:RDF>`,
+			items:  1,
+			issues: []rss.Issue{},
+		},
+		{
+			name:  "repaired",
+			input: `<rss version="2.0"><channel><title>Q & A</title><description>D</description><link>https://example.com/</link></channel></rss>`,
+			issues: []rss.Issue{
+				{Field: "document", Reason: "document is not well-formed: repaired unescaped ampersand (1)", Severity: rss.SeverityWarning, Spec: "https://www.w3.org/TR/xml/#sec-well-formed"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := parser.Load(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			feed, err := rss.NewFromFeed(parsed)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(feed.Items) != tc.items {
+				t.Errorf("expected %d items, got %d", tc.items, len(feed.Items))
+			}
+			if feed.Report.Format != parsed.Format {
+				t.Errorf("expected format %q, got %q", parsed.Format, feed.Report.Format)
+			}
+			if !reflect.DeepEqual(feed.Report.Issues, tc.issues) {
+				t.Errorf("expected issues %+v, got %+v", tc.issues, feed.Report.Issues)
+			}
+		})
+	}
+}
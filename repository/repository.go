@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
 	"github.com/haleyrc/rss"
 )
@@ -12,6 +15,12 @@ const (
 	AllItems int = 0
 )
 
+// feedColumns are the columns selected whenever feeds are loaded.
+const feedColumns = `id, title, description, link, icon AS image, url, etag, last_modified, fetched_at, next_fetch, moved_at, schedule, report, rejected_self_link`
+
+// itemColumns are the columns selected whenever items are loaded.
+const itemColumns = `id, feed_id, guid, title, link, publication_date, summary, content, read, ignored, starred, duration, episode, episode_image, transcript_url, transcript_type, chapters_url, media, lead_image`
+
 func New(db *sqlx.DB) rss.Repository {
 	return &repository{db}
 }
@@ -20,24 +29,337 @@ type repository struct {
 	db *sqlx.DB
 }
 
+func (r *repository) GetFeed(id int64) (*rss.Feed, error) {
+	q := `SELECT ` + feedColumns + ` FROM feeds WHERE id = $1`
+	var feed rss.Feed
+	if err := r.db.Get(&feed, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rss.ErrFeedNotFound
+		}
+		return nil, err
+	}
+	if err := loadAliases(r.db, &feed); err != nil {
+		return nil, err
+	}
+	if err := loadFeedCategories(r.db, &feed); err != nil {
+		return nil, err
+	}
+	if err := loadFeedAuthors(r.db, &feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// FindFeedByURL returns the feed fetched from url, or that used to be.
+func (r *repository) FindFeedByURL(url string) (*rss.Feed, error) {
+	q := `SELECT ` + feedColumns + ` FROM feeds WHERE url = $1 OR id = (SELECT feed_id FROM feed_aliases WHERE url = $1) LIMIT 1`
+	var feed rss.Feed
+	if err := r.db.Get(&feed, q, url); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rss.ErrFeedNotFound
+		}
+		return nil, err
+	}
+	if err := loadAliases(r.db, &feed); err != nil {
+		return nil, err
+	}
+	if err := loadFeedCategories(r.db, &feed); err != nil {
+		return nil, err
+	}
+	if err := loadFeedAuthors(r.db, &feed); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// MoveFeed changes the address feed is fetched from to url, keeping the old
+// address as an alias.
+func (r *repository) MoveFeed(feed *rss.Feed, url string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if feed.URL != "" {
+		q := `INSERT INTO feed_aliases (url, feed_id) VALUES ($1, $2) ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id`
+		if _, err := tx.Exec(q, feed.URL, feed.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// A feed may move back to an address it used before.
+	q := `DELETE FROM feed_aliases WHERE url = $1`
+	if _, err := tx.Exec(q, url); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	q = `UPDATE feeds SET url = $2, moved_at = NOW() WHERE id = $1 RETURNING moved_at`
+	if err := tx.Get(&feed.MovedAt, q, feed.ID, url); err != nil {
+		tx.Rollback()
+		return err
+	}
+	feed.URL = url
+
+	if err := loadAliases(tx, feed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func loadAliases(s Selecter, feed *rss.Feed) error {
+	q := `SELECT url FROM feed_aliases WHERE feed_id = $1 ORDER BY url`
+	feed.Aliases = []string{}
+	return s.Select(&feed.Aliases, q, feed.ID)
+}
+
+func loadFeedCategories(s Selecter, feed *rss.Feed) error {
+	q := `SELECT c.id, c.name, c.domain FROM feed_categories fc JOIN categories c ON c.id = fc.category_id WHERE fc.feed_id = $1 ORDER BY c.name`
+	feed.Categories = []*rss.Category{}
+	return s.Select(&feed.Categories, q, feed.ID)
+}
+
+func loadFeedAuthors(s Selecter, feed *rss.Feed) error {
+	q := `SELECT a.id, a.name, a.email, a.url, a.avatar FROM feed_authors fa JOIN authors a ON a.id = fa.author_id WHERE fa.feed_id = $1 ORDER BY fa.position`
+	feed.Authors = []*rss.Author{}
+	return s.Select(&feed.Authors, q, feed.ID)
+}
+
+// UpdateFeedCache stores what was learned about when to fetch a feed again:
+// its HTTP validators, last and next fetch times and publishing schedule,
+// along with the validation report from the fetch and any self link that was
+// rejected.
+func (r *repository) UpdateFeedCache(feed *rss.Feed) error {
+	q := `UPDATE feeds SET etag = $2, last_modified = $3, next_fetch = $4, schedule = $5, report = $6, rejected_self_link = $7, fetched_at = $8 WHERE id = $1`
+	_, err := r.db.Exec(q, feed.ID, feed.ETag, feed.LastModified, feed.NextFetch, feed.Schedule, feed.Report, feed.RejectedSelfLink, feed.FetchedAt)
+	return err
+}
+
 func (r *repository) RemoveFeed(id int64) error {
 	q := `DELETE FROM feeds WHERE id = $1`
 	_, err := r.db.Exec(q, id)
 	return err
 }
 
+// subscriptionColumns are the columns selected whenever subscriptions are
+// loaded.
+const subscriptionColumns = `feed_id, hub, topic, secret, state, lease_expires, requested_at, attempts`
+
+// SaveSubscription creates the subscription to a feed, or replaces it if the
+// feed already has one.
+func (r *repository) SaveSubscription(sub *rss.Subscription) error {
+	q := `INSERT INTO subscriptions (` + subscriptionColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (feed_id) DO UPDATE SET hub = EXCLUDED.hub, topic = EXCLUDED.topic, secret = EXCLUDED.secret, state = EXCLUDED.state, lease_expires = EXCLUDED.lease_expires, requested_at = EXCLUDED.requested_at, attempts = EXCLUDED.attempts`
+	_, err := r.db.Exec(q, sub.FeedID, sub.Hub, sub.Topic, sub.Secret, sub.State, sub.LeaseExpires, sub.RequestedAt, sub.Attempts)
+	return err
+}
+
+func (r *repository) GetSubscription(feedID int64) (*rss.Subscription, error) {
+	q := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE feed_id = $1`
+	var sub rss.Subscription
+	if err := r.db.Get(&sub, q, feedID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rss.ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListSubscriptionsExpiring returns the active subscriptions whose lease runs
+// out before the given time, soonest first.
+func (r *repository) ListSubscriptionsExpiring(before time.Time) ([]*rss.Subscription, error) {
+	q := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE state = $1 AND lease_expires < $2 ORDER BY lease_expires`
+	subs := []*rss.Subscription{}
+	if err := r.db.Select(&subs, q, rss.SubscriptionActive, before); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *repository) RemoveSubscription(feedID int64) error {
+	q := `DELETE FROM subscriptions WHERE feed_id = $1`
+	_, err := r.db.Exec(q, feedID)
+	return err
+}
+
+// cloudRegistrationColumns are the columns selected whenever rssCloud
+// registrations are loaded.
+const cloudRegistrationColumns = `feed_id, endpoint, topic, registered_at`
+
+// SaveCloudRegistration creates the rssCloud registration for a feed, or
+// replaces it if the feed already has one.
+func (r *repository) SaveCloudRegistration(reg *rss.CloudRegistration) error {
+	q := `INSERT INTO cloud_registrations (` + cloudRegistrationColumns + `) VALUES ($1, $2, $3, $4) ON CONFLICT (feed_id) DO UPDATE SET endpoint = EXCLUDED.endpoint, topic = EXCLUDED.topic, registered_at = EXCLUDED.registered_at`
+	_, err := r.db.Exec(q, reg.FeedID, reg.Endpoint, reg.Topic, reg.RegisteredAt)
+	return err
+}
+
+func (r *repository) GetCloudRegistration(feedID int64) (*rss.CloudRegistration, error) {
+	q := `SELECT ` + cloudRegistrationColumns + ` FROM cloud_registrations WHERE feed_id = $1`
+	var reg rss.CloudRegistration
+	if err := r.db.Get(&reg, q, feedID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, rss.ErrRegistrationNotFound
+		}
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// ListCloudRegistrationsBefore returns the accepted rssCloud registrations
+// last made before the given time, oldest first.
+func (r *repository) ListCloudRegistrationsBefore(before time.Time) ([]*rss.CloudRegistration, error) {
+	q := `SELECT ` + cloudRegistrationColumns + ` FROM cloud_registrations WHERE registered_at < $1 ORDER BY registered_at`
+	regs := []*rss.CloudRegistration{}
+	if err := r.db.Select(&regs, q, before); err != nil {
+		return nil, err
+	}
+	return regs, nil
+}
+
+func (r *repository) RemoveCloudRegistration(feedID int64) error {
+	q := `DELETE FROM cloud_registrations WHERE feed_id = $1`
+	_, err := r.db.Exec(q, feedID)
+	return err
+}
+
 func (r *repository) ListItems(limit int) ([]*rss.Item, error) {
-	q := `SELECT id, feed_id, title, link, publication_date, read, ignored, starred FROM items ORDER BY publication_date DESC`
+	q := `SELECT ` + itemColumns + ` FROM items ORDER BY publication_date DESC`
+	if limit != AllItems {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return r.selectItems(q)
+}
+
+// ListItemsByCategory returns the items filed under category in any domain,
+// most recent first. The category name is matched without regard to case.
+func (r *repository) ListItemsByCategory(category string, limit int) ([]*rss.Item, error) {
+	q := `SELECT ` + itemColumns + ` FROM items WHERE id IN (SELECT ic.item_id FROM item_categories ic JOIN categories c ON c.id = ic.category_id WHERE LOWER(c.name) = LOWER($1)) ORDER BY publication_date DESC`
 	if limit != AllItems {
-		q += fmt.Sprintf("LIMIT %d", limit)
+		q += fmt.Sprintf(" LIMIT %d", limit)
 	}
+	return r.selectItems(q, category)
+}
+
+// ListItemsByAuthor returns the items credited to author, most recent first.
+// The author may be given by name or by email address, and is matched without
+// regard to case.
+func (r *repository) ListItemsByAuthor(author string, limit int) ([]*rss.Item, error) {
+	q := `SELECT ` + itemColumns + ` FROM items WHERE id IN (SELECT ia.item_id FROM item_authors ia JOIN authors a ON a.id = ia.author_id WHERE LOWER(a.name) = LOWER($1) OR LOWER(a.email) = LOWER($1)) ORDER BY publication_date DESC`
+	if limit != AllItems {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return r.selectItems(q, author)
+}
+
+// selectItems runs a query for items and attaches everything stored alongside
+// them.
+func (r *repository) selectItems(q string, args ...interface{}) ([]*rss.Item, error) {
 	var items []*rss.Item
-	if err := r.db.Select(&items, q); err != nil {
+	if err := r.db.Select(&items, q, args...); err != nil {
+		return nil, err
+	}
+	if err := r.loadEnclosures(items...); err != nil {
+		return nil, err
+	}
+	if err := r.loadCategories(items...); err != nil {
+		return nil, err
+	}
+	if err := r.loadAuthors(items...); err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+// loadEnclosures fetches the enclosures for all of the given items in a single
+// query and attaches them.
+func (r *repository) loadEnclosures(items ...*rss.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*rss.Item, len(items))
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+		ids = append(ids, item.ID)
+	}
+
+	q := `SELECT id, item_id, url, type, length FROM enclosures WHERE item_id = ANY($1) ORDER BY id`
+	var enclosures []*rss.Enclosure
+	if err := r.db.Select(&enclosures, q, pq.Array(ids)); err != nil {
+		return err
+	}
+	for _, e := range enclosures {
+		item := byID[e.ItemID]
+		item.Enclosures = append(item.Enclosures, e)
+	}
+	return nil
+}
+
+// loadCategories fetches the categories for all of the given items in a single
+// query and attaches them.
+func (r *repository) loadCategories(items ...*rss.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*rss.Item, len(items))
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+		ids = append(ids, item.ID)
+	}
+
+	q := `SELECT ic.item_id, c.id, c.name, c.domain FROM item_categories ic JOIN categories c ON c.id = ic.category_id WHERE ic.item_id = ANY($1) ORDER BY c.name`
+	var rows []struct {
+		ItemID int64 `db:"item_id"`
+		rss.Category
+	}
+	if err := r.db.Select(&rows, q, pq.Array(ids)); err != nil {
+		return err
+	}
+	for i := range rows {
+		item := byID[rows[i].ItemID]
+		item.Categories = append(item.Categories, &rows[i].Category)
+	}
+	return nil
+}
+
+// loadAuthors fetches the authors for all of the given items in a single query
+// and attaches them in the order they were credited.
+func (r *repository) loadAuthors(items ...*rss.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*rss.Item, len(items))
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+		ids = append(ids, item.ID)
+	}
+
+	q := `SELECT ia.item_id, a.id, a.name, a.email, a.url, a.avatar FROM item_authors ia JOIN authors a ON a.id = ia.author_id WHERE ia.item_id = ANY($1) ORDER BY ia.position`
+	var rows []struct {
+		ItemID int64 `db:"item_id"`
+		rss.Author
+	}
+	if err := r.db.Select(&rows, q, pq.Array(ids)); err != nil {
+		return err
+	}
+	for i := range rows {
+		item := byID[rows[i].ItemID]
+		item.Authors = append(item.Authors, &rows[i].Author)
+	}
+	return nil
+}
+
 func (r *repository) setItemRead(id int64, status bool) error {
 	q := `UPDATE items SET read = $2 WHERE id = $1`
 	_, err := r.db.Exec(q, id, status)
@@ -83,28 +405,214 @@ type Getter interface {
 	Get(dest interface{}, q string, args ...interface{}) error
 }
 
+type Execer interface {
+	Exec(q string, args ...interface{}) (sql.Result, error)
+}
+type Selecter interface {
+	Select(dest interface{}, q string, args ...interface{}) error
+}
+
+type GetExecer interface {
+	Getter
+	Execer
+}
+
 func (r *repository) GetItem(id int64) (*rss.Item, error) {
-	q := `SELECT feed_id, title, link, publication_date, read, ignored, starred FROM items WHERE id = $1`
+	q := `SELECT ` + itemColumns + ` FROM items WHERE id = $1`
 	var item rss.Item
 	if err := r.db.Get(&item, q, id); err != nil {
 		return nil, err
 	}
+	if err := r.loadEnclosures(&item); err != nil {
+		return nil, err
+	}
+	if err := r.loadCategories(&item); err != nil {
+		return nil, err
+	}
+	if err := r.loadAuthors(&item); err != nil {
+		return nil, err
+	}
 	return &item, nil
 }
 
-func createItem(g Getter, item *rss.Item) error {
-	q := `INSERT INTO items (feed_id, title, link, publication_date) VALUES ($1, $2, $3, $4) ON CONFLICT (feed_id, link) DO UPDATE SET title=EXCLUDED.title, publication_date=EXCLUDED.publication_date RETURNING id`
-	return g.Get(item, q, item.FeedID, item.Title, item.Link, item.PublicationDate)
+// createItem upserts an item and its enclosures. Items are deduplicated within
+// their feed on the GUID, or on the link when the feed does not publish GUIDs,
+// which must match the uniq_feed_item_identity index.
+func createItem(g GetExecer, item *rss.Item) error {
+	q := `INSERT INTO items (feed_id, guid, title, link, publication_date, summary, content, duration, episode, episode_image, transcript_url, transcript_type, chapters_url, media, lead_image) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) ON CONFLICT (feed_id, (COALESCE(NULLIF(guid, ''), link))) DO UPDATE SET title=EXCLUDED.title, link=EXCLUDED.link, publication_date=EXCLUDED.publication_date, summary=EXCLUDED.summary, content=EXCLUDED.content, duration=EXCLUDED.duration, episode=EXCLUDED.episode, episode_image=EXCLUDED.episode_image, transcript_url=EXCLUDED.transcript_url, transcript_type=EXCLUDED.transcript_type, chapters_url=EXCLUDED.chapters_url, media=EXCLUDED.media, lead_image=EXCLUDED.lead_image RETURNING id`
+	if err := g.Get(item, q, item.FeedID, item.GUID, item.Title, item.Link, item.PublicationDate, item.Summary, item.Content, item.Duration, item.Episode, item.Podcast.Image, item.TranscriptURL, item.TranscriptType, item.ChaptersURL, item.Media, item.LeadImage); err != nil {
+		return err
+	}
+	if err := setEnclosures(g, item); err != nil {
+		return err
+	}
+	if err := setItemCategories(g, item); err != nil {
+		return err
+	}
+	return setItemAuthors(g, item)
+}
+
+// setEnclosures replaces the stored enclosures for an item with those
+// currently attached to it.
+func setEnclosures(g GetExecer, item *rss.Item) error {
+	urls := make([]string, 0, len(item.Enclosures))
+	for _, e := range item.Enclosures {
+		urls = append(urls, e.URL)
+	}
+	q := `DELETE FROM enclosures WHERE item_id = $1 AND NOT (url = ANY($2))`
+	if _, err := g.Exec(q, item.ID, pq.Array(urls)); err != nil {
+		return err
+	}
+
+	q = `INSERT INTO enclosures (item_id, url, type, length) VALUES ($1, $2, $3, $4) ON CONFLICT (item_id, url) DO UPDATE SET type=EXCLUDED.type, length=EXCLUDED.length RETURNING id`
+	for _, e := range item.Enclosures {
+		e.ItemID = item.ID
+		if err := g.Get(e, q, e.ItemID, e.URL, e.Type, e.Length); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveCategories stores any of the given categories that are not yet known and
+// sets their IDs.
+func saveCategories(g Getter, categories []*rss.Category) error {
+	q := `INSERT INTO categories (name, domain) VALUES ($1, $2) ON CONFLICT ((LOWER(name)), domain) DO UPDATE SET name = categories.name RETURNING id`
+	for _, c := range categories {
+		if err := g.Get(&c.ID, q, c.Name, c.Domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func categoryIDs(categories []*rss.Category) []int64 {
+	ids := make([]int64, 0, len(categories))
+	for _, c := range categories {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// setItemCategories replaces the categories an item is filed under with those
+// currently attached to it.
+func setItemCategories(g GetExecer, item *rss.Item) error {
+	if err := saveCategories(g, item.Categories); err != nil {
+		return err
+	}
+	ids := categoryIDs(item.Categories)
+	q := `DELETE FROM item_categories WHERE item_id = $1 AND NOT (category_id = ANY($2))`
+	if _, err := g.Exec(q, item.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+	q = `INSERT INTO item_categories (item_id, category_id) SELECT $1::INTEGER, UNNEST($2::INTEGER[]) ON CONFLICT DO NOTHING`
+	_, err := g.Exec(q, item.ID, pq.Array(ids))
+	return err
+}
+
+// setFeedCategories replaces the categories a feed is filed under with those
+// currently attached to it.
+func setFeedCategories(g GetExecer, feed *rss.Feed) error {
+	if err := saveCategories(g, feed.Categories); err != nil {
+		return err
+	}
+	ids := categoryIDs(feed.Categories)
+	q := `DELETE FROM feed_categories WHERE feed_id = $1 AND NOT (category_id = ANY($2))`
+	if _, err := g.Exec(q, feed.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+	q = `INSERT INTO feed_categories (feed_id, category_id) SELECT $1::INTEGER, UNNEST($2::INTEGER[]) ON CONFLICT DO NOTHING`
+	_, err := g.Exec(q, feed.ID, pq.Array(ids))
+	return err
+}
+
+// saveAuthors stores any of the given authors that are not yet known and sets
+// their IDs. Links and avatars are updated for those that are, unless they
+// have been left out this time.
+func saveAuthors(g Getter, authors []*rss.Author) error {
+	q := `INSERT INTO authors (name, email, url, avatar) VALUES ($1, $2, $3, $4) ON CONFLICT ((LOWER(name)), (LOWER(email))) DO UPDATE SET url = COALESCE(NULLIF(EXCLUDED.url, ''), authors.url), avatar = COALESCE(NULLIF(EXCLUDED.avatar, ''), authors.avatar) RETURNING id`
+	for _, a := range authors {
+		if err := g.Get(&a.ID, q, a.Name, a.Email, a.URL, a.Avatar); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+func authorIDs(authors []*rss.Author) []int64 {
+	ids := make([]int64, 0, len(authors))
+	for _, a := range authors {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+// setItemAuthors replaces the authors credited with an item with those
+// currently attached to it.
+func setItemAuthors(g GetExecer, item *rss.Item) error {
+	if err := saveAuthors(g, item.Authors); err != nil {
+		return err
+	}
+	ids := authorIDs(item.Authors)
+	q := `DELETE FROM item_authors WHERE item_id = $1 AND NOT (author_id = ANY($2))`
+	if _, err := g.Exec(q, item.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+	q = `INSERT INTO item_authors (item_id, author_id, position) SELECT $1::INTEGER, a.id, a.position FROM UNNEST($2::INTEGER[]) WITH ORDINALITY AS a(id, position) ON CONFLICT (item_id, author_id) DO UPDATE SET position = EXCLUDED.position`
+	_, err := g.Exec(q, item.ID, pq.Array(ids))
+	return err
+}
+
+// setFeedAuthors replaces the authors credited with a feed with those
+// currently attached to it.
+func setFeedAuthors(g GetExecer, feed *rss.Feed) error {
+	if err := saveAuthors(g, feed.Authors); err != nil {
+		return err
+	}
+	ids := authorIDs(feed.Authors)
+	q := `DELETE FROM feed_authors WHERE feed_id = $1 AND NOT (author_id = ANY($2))`
+	if _, err := g.Exec(q, feed.ID, pq.Array(ids)); err != nil {
+		return err
+	}
+	q = `INSERT INTO feed_authors (feed_id, author_id, position) SELECT $1::INTEGER, a.id, a.position FROM UNNEST($2::INTEGER[]) WITH ORDINALITY AS a(id, position) ON CONFLICT (feed_id, author_id) DO UPDATE SET position = EXCLUDED.position`
+	_, err := g.Exec(q, feed.ID, pq.Array(ids))
+	return err
+}
+
+// CreateFeed stores feed and its items. Feeds are identified by the address
+// they are fetched from, so a feed fetched from the same address as an existing
+// one, or from one of its aliases, updates that feed instead.
 func (r *repository) CreateFeed(feed *rss.Feed, items ...*rss.Item) error {
 	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
 
-	q := `INSERT INTO feeds (title, description, link, icon) VALUES ($1, $2, $3, $4) ON CONFLICT (link) DO UPDATE SET description=EXCLUDED.description, title=EXCLUDED.title, icon=EXCLUDED.icon RETURNING id`
-	if err := tx.Get(feed, q, feed.Title, feed.Description, feed.Link, feed.Image); err != nil {
+	if feed.URL != "" {
+		var moved string
+		q := `SELECT f.url FROM feeds f JOIN feed_aliases a ON a.feed_id = f.id WHERE a.url = $1`
+		err := tx.Get(&moved, q, feed.URL)
+		if err != nil && err != sql.ErrNoRows {
+			tx.Rollback()
+			return err
+		}
+		if err == nil {
+			feed.URL = moved
+		}
+	}
+
+	q := `INSERT INTO feeds (title, description, link, icon, url, etag, last_modified, next_fetch, schedule, report, fetched_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT (url) WHERE url <> '' DO UPDATE SET description=EXCLUDED.description, title=EXCLUDED.title, link=EXCLUDED.link, icon=EXCLUDED.icon, etag=EXCLUDED.etag, last_modified=EXCLUDED.last_modified, next_fetch=EXCLUDED.next_fetch, schedule=EXCLUDED.schedule, report=EXCLUDED.report, fetched_at=EXCLUDED.fetched_at RETURNING id`
+	if err := tx.Get(feed, q, feed.Title, feed.Description, feed.Link, feed.Image, feed.URL, feed.ETag, feed.LastModified, feed.NextFetch, feed.Schedule, feed.Report, feed.FetchedAt); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := setFeedCategories(tx, feed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := setFeedAuthors(tx, feed); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -126,5 +634,20 @@ func (r *repository) CreateFeed(feed *rss.Feed, items ...*rss.Item) error {
 }
 
 func (r *repository) CreateItem(item *rss.Item) error {
-	return createItem(r.db, item)
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := createItem(tx, item); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
 }
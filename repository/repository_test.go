@@ -35,6 +35,8 @@ func TestFeedItems(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	item2.Summary = "A short summary."
+	item2.Content = "<p>The full content.</p>"
 
 	if err := client.CreateItem(item1); err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -58,6 +60,12 @@ func TestFeedItems(t *testing.T) {
 	if got.Link != item2.Link {
 		t.Errorf("expected link %q, got %q", item2.Link, got.Link)
 	}
+	if got.Summary != item2.Summary {
+		t.Errorf("expected summary %q, got %q", item2.Summary, got.Summary)
+	}
+	if got.Content != item2.Content {
+		t.Errorf("expected content %q, got %q", item2.Content, got.Content)
+	}
 	wantDate := item2.PublicationDate.Round(time.Millisecond)
 	gotDate := got.PublicationDate.Round(time.Millisecond)
 	if !gotDate.Equal(wantDate) {
@@ -65,6 +73,136 @@ func TestFeedItems(t *testing.T) {
 	}
 }
 
+func TestItemGUIDs(t *testing.T) {
+	db := sqlx.MustConnect("postgres", "host=localhost user=postgres password=test port=5433 dbname=rss sslmode=disable")
+	client := repository.New(db)
+
+	feed, err := rss.NewFeed("guid feed", "this is a test", "http://example.com/guids", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.CreateFeed(feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newItem := func(guid, link string) *rss.Item {
+		item, err := rss.NewItem(feed.ID, "Episode", link, time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		item.GUID = guid
+		if err := client.CreateItem(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return item
+	}
+
+	episode1 := newItem("episode-1", "http://example.com/podcast")
+	episode2 := newItem("episode-2", "http://example.com/podcast")
+	if episode1.ID == episode2.ID {
+		t.Errorf("expected items with the same link but different guids to be distinct")
+	}
+
+	moved := newItem("episode-1", "http://example.com/podcast/episode-1")
+	if moved.ID != episode1.ID {
+		t.Errorf("expected ids to be equal, %d != %d", episode1.ID, moved.ID)
+	}
+
+	got, err := client.GetItem(episode1.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Link != moved.Link {
+		t.Errorf("expected link %q, got %q", moved.Link, got.Link)
+	}
+}
+
+func TestMoveFeed(t *testing.T) {
+	db := sqlx.MustConnect("postgres", "host=localhost user=postgres password=test port=5433 dbname=rss sslmode=disable")
+	client := repository.New(db)
+
+	feed, err := rss.NewFeed("moving feed", "this is a test", "http://example.com/moving", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed.URL = "http://example.com/moving/old.xml"
+	if err := client.CreateFeed(feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.MoveFeed(feed, "http://example.com/moving/new.xml"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := client.FindFeedByURL("http://example.com/moving/old.xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != feed.ID {
+		t.Errorf("expected feed %d, got %d", feed.ID, got.ID)
+	}
+	if want := "http://example.com/moving/new.xml"; got.URL != want {
+		t.Errorf("expected url %q, got %q", want, got.URL)
+	}
+	if len(got.Aliases) != 1 || got.Aliases[0] != "http://example.com/moving/old.xml" {
+		t.Errorf("expected old url as the only alias, got %q", got.Aliases)
+	}
+	if got.MovedAt == nil {
+		t.Errorf("expected moved at to be set")
+	}
+
+	if _, err := client.FindFeedByURL("http://example.com/moving/unknown.xml"); err != rss.ErrFeedNotFound {
+		t.Errorf("expected error %v, got %v", rss.ErrFeedNotFound, err)
+	}
+}
+
+func TestCreateFeedSameLink(t *testing.T) {
+	db := sqlx.MustConnect("postgres", "host=localhost user=postgres password=test port=5433 dbname=rss sslmode=disable")
+	client := repository.New(db)
+
+	rssFeed, err := rss.NewFeed("rss feed", "this is a test", "http://example.com/twofeeds", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rssFeed.URL = "http://example.com/twofeeds/rss.xml"
+	if err := client.CreateFeed(rssFeed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	atomFeed, err := rss.NewFeed("atom feed", "this is a test", "http://example.com/twofeeds", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	atomFeed.URL = "http://example.com/twofeeds/atom.xml"
+	if err := client.CreateFeed(atomFeed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rssFeed.ID == atomFeed.ID {
+		t.Fatalf("expected feeds with different urls to be stored separately, both got id %d", rssFeed.ID)
+	}
+	got, err := client.GetFeed(rssFeed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.URL != rssFeed.URL {
+		t.Errorf("expected url %q, got %q", rssFeed.URL, got.URL)
+	}
+
+	again, err := rss.NewFeed("rss feed", "this is a test", "http://example.com/twofeeds", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again.URL = rssFeed.URL
+	if err := client.CreateFeed(again); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again.ID != rssFeed.ID {
+		t.Errorf("expected the same url to update feed %d, got %d", rssFeed.ID, again.ID)
+	}
+}
+
 func TestRepository(t *testing.T) {
 	db := sqlx.MustConnect("postgres", "host=localhost user=postgres password=test port=5433 dbname=rss sslmode=disable")
 	client := repository.New(db)
@@ -88,3 +226,108 @@ func TestRepository(t *testing.T) {
 		}
 	}
 }
+
+func TestCategories(t *testing.T) {
+	db := sqlx.MustConnect("postgres", "host=localhost user=postgres password=test port=5433 dbname=rss sslmode=disable")
+	client := repository.New(db)
+
+	feed, err := rss.NewFeed("categorised feed", "this is a test", "http://example.com/categorised", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed.Categories = []*rss.Category{{Name: "Technology"}}
+	golang, err := rss.NewItem(1, "go post", "http://example.com/categorised/go", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	golang.Categories = []*rss.Category{{Name: "Golang"}, {Name: "Programming", Domain: "http://example.com/tags"}}
+	rust, err := rss.NewItem(1, "rust post", "http://example.com/categorised/rust", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rust.Categories = []*rss.Category{{Name: "programming", Domain: "http://example.com/tags"}}
+	if err := client.CreateFeed(feed, golang, rust); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if golang.Categories[1].ID != rust.Categories[0].ID {
+		t.Errorf("expected categories differing only in case to be shared")
+	}
+
+	items, err := client.ListItemsByCategory("GOLANG", repository.AllItems)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != golang.ID {
+		t.Fatalf("expected only item %d, got %v", golang.ID, items)
+	}
+	if len(items[0].Categories) != 2 {
+		t.Errorf("expected 2 categories, got %d", len(items[0].Categories))
+	}
+
+	items, err = client.ListItemsByCategory("Programming", repository.AllItems)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 items, got %d", len(items))
+	}
+
+	got, err := client.GetFeed(feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Categories) != 1 || got.Categories[0].Name != "Technology" {
+		t.Errorf("expected feed category %q, got %v", "Technology", got.Categories)
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	db := sqlx.MustConnect("postgres", "host=localhost user=postgres password=test port=5433 dbname=rss sslmode=disable")
+	client := repository.New(db)
+
+	feed, err := rss.NewFeed("authored feed", "this is a test", "http://example.com/authored", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed.Authors = []*rss.Author{{Name: "The Editor", Email: "editor@example.com"}}
+	first, err := rss.NewItem(1, "first post", "http://example.com/authored/1", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first.Authors = []*rss.Author{{Name: "Sam"}, {Name: "Jo", Email: "jo@example.com"}}
+	second, err := rss.NewItem(1, "second post", "http://example.com/authored/2", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second.Authors = []*rss.Author{{Name: "jo", Email: "JO@example.com", Avatar: "http://example.com/jo.png"}}
+	if err := client.CreateFeed(feed, first, second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, err := client.ListItemsByAuthor("jo@example.com", repository.AllItems)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	got, err := client.GetItem(first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Authors) != 2 || got.Authors[0].Name != "Sam" || got.Authors[1].Name != "Jo" {
+		t.Fatalf("expected authors Sam and Jo in order, got %+v", got.Authors)
+	}
+	if want := "http://example.com/jo.png"; got.Authors[1].Avatar != want {
+		t.Errorf("expected avatar %q, got %q", want, got.Authors[1].Avatar)
+	}
+
+	gotFeed, err := client.GetFeed(feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gotFeed.Authors) != 1 || gotFeed.Authors[0].Name != "The Editor" {
+		t.Errorf("expected feed author %q, got %+v", "The Editor", gotFeed.Authors)
+	}
+}
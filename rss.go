@@ -2,7 +2,6 @@ package rss
 
 import (
 	"errors"
-	"log"
 	"strings"
 	"time"
 
@@ -14,6 +13,9 @@ var (
 	ErrDescriptionIsRequired = errors.New("description is required")
 	ErrLinkRequired          = errors.New("link is required")
 	ErrFeedRequired          = errors.New("feed is required")
+	ErrFeedNotFound          = errors.New("feed not found")
+	ErrSubscriptionNotFound  = errors.New("subscription not found")
+	ErrRegistrationNotFound  = errors.New("cloud registration not found")
 )
 
 type Repository interface {
@@ -28,6 +30,20 @@ type Repository interface {
 	StarItem(id int64) error
 	UnstarItem(id int64) error
 	ListItems(limit int) ([]*Item, error)
+	ListItemsByCategory(category string, limit int) ([]*Item, error)
+	ListItemsByAuthor(author string, limit int) ([]*Item, error)
+	GetFeed(id int64) (*Feed, error)
+	UpdateFeedCache(feed *Feed) error
+	FindFeedByURL(url string) (*Feed, error)
+	MoveFeed(feed *Feed, url string) error
+	SaveSubscription(sub *Subscription) error
+	GetSubscription(feedID int64) (*Subscription, error)
+	ListSubscriptionsExpiring(before time.Time) ([]*Subscription, error)
+	RemoveSubscription(feedID int64) error
+	SaveCloudRegistration(reg *CloudRegistration) error
+	GetCloudRegistration(feedID int64) (*CloudRegistration, error)
+	ListCloudRegistrationsBefore(before time.Time) ([]*CloudRegistration, error)
+	RemoveCloudRegistration(feedID int64) error
 }
 
 func NewFeed(title, description, link, image string, items ...*Item) (*Feed, error) {
@@ -53,35 +69,166 @@ func NewFeed(title, description, link, image string, items ...*Item) (*Feed, err
 	}, nil
 }
 
+// NewFromChannel converts a parsed channel whose format is not known. See
+// NewFromFeed.
 func NewFromChannel(c parser.Channel) (*Feed, error) {
+	return NewFromFeed(parser.Feed{Channel: c})
+}
+
+// NewFromFeed converts a parsed feed. Items that can not be converted are left
+// out rather than failing the whole feed; why, along with anything else in the
+// feed that breaks its format's specification, is recorded in the feed's
+// Report. An error is only returned if the channel itself is unusable.
+func NewFromFeed(f parser.Feed) (*Feed, error) {
+	c := f.Channel
+	report := Report{CheckedAt: time.Now(), Format: f.Format, Issues: []Issue{}}
+	report.addRepairs(f.Repairs)
+
+	// Relative references are resolved against the xml:base in scope, which
+	// the parser has already resolved against the address the feed was
+	// fetched from. Feeds loaded from elsewhere fall back to the channel
+	// link, which is the best guess at where they were published.
+	base := c.Base
+	channelLink := parser.ResolveURL(base, c.Link)
+	if base == "" {
+		base = channelLink
+	}
+
+	// JSON Feed and RSS 1.0 do not require items to have a date, so their
+	// items are kept without one and dated when we first see them.
+	datesOptional := f.Format == parser.FormatJSON || f.Format == parser.FormatRDF
+
 	var items []*Item
-	for _, item := range c.Items {
-		pubDate, err := time.Parse(time.RFC1123, item.PublicationDate)
-		if err != nil {
-			log.Printf("error parsing publication date: %s: %v: skipping\n", item.PublicationDate, err)
-			continue
+	guids := make(map[string]int)
+	for i, item := range c.Items {
+		position := i + 1
+		var pubDate time.Time
+		if strings.TrimSpace(item.PublicationDate) == "" {
+			if !datesOptional {
+				report.add(position, fieldDate, SeverityError, "", "item has no publication date and was skipped")
+				continue
+			}
+		} else if date, layout, err := parser.ParseDate(item.PublicationDate); err == parser.ErrUnknownZone {
+			pubDate = date
+			report.add(position, fieldDate, SeverityWarning, item.PublicationDate, "time zone is not known, so the date was read as UTC")
+		} else if err != nil {
+			if !datesOptional {
+				report.add(position, fieldDate, SeverityError, item.PublicationDate, "publication date could not be understood and the item was skipped")
+				continue
+			}
+			report.add(position, fieldDate, SeverityWarning, item.PublicationDate, "publication date could not be understood and was ignored")
+		} else {
+			pubDate = date
+			report.checkDateLayout(position, item.PublicationDate, layout)
+		}
+		itemBase := base
+		if item.Base != "" {
+			itemBase = parser.ResolveURL(base, item.Base)
 		}
-		newItem, err := NewItem(-1, item.Title, item.Link, pubDate)
+		link := parser.ResolveURL(itemBase, item.Link)
+		if link == "" && item.GUID.PermaLink() {
+			link = parser.ResolveURL(itemBase, item.GUID.Value)
+		}
+		newItem, err := NewItem(-1, item.Title, link, pubDate)
 		if err != nil {
-			log.Printf("invalid item: %v: skipping\n", err)
+			field := fieldTitle
+			if err == ErrLinkRequired {
+				field = fieldLink
+			}
+			report.add(position, field, SeverityError, "", "item was skipped: %v", err)
 			continue
 		}
+		if guid := item.GUID.Value; guid != "" {
+			if first, ok := guids[guid]; ok {
+				report.add(position, fieldGUID, SeverityWarning, guid, "item has the same identifier as item %d and will replace it", first)
+			} else {
+				guids[guid] = position
+			}
+		}
+		if itemBase == "" {
+			itemBase = newItem.Link
+		}
+		newItem.GUID = item.GUID.Value
+		newItem.Summary = ReadingPolicy.Sanitize(item.Description, itemBase)
+		newItem.Content = ReadingPolicy.Sanitize(item.Content, itemBase)
+		newItem.Authors = newAuthors(item.Authors, itemBase)
+		newItem.Categories = newCategories(item.Categories)
+		newItem.Enclosures = newEnclosures(item.Enclosures, itemBase)
+		newItem.Podcast = newPodcast(item, itemBase)
+		newItem.Media = newMedia(item.Media, itemBase)
+		newItem.LeadImage = leadImage(newItem)
 		items = append(items, newItem)
 	}
-	feed, err := NewFeed(c.Title, c.Description, c.Link, c.Image, items...)
+	// Only RSS requires a channel description, so fall back to the title for
+	// Atom and JSON feeds that do not publish one.
+	description := c.Description
+	if description == "" {
+		description = c.Title
+		if f.Format == parser.FormatRSS || f.Format == parser.FormatRDF {
+			report.add(0, fieldDescription, SeverityWarning, "", "channel has no description")
+		}
+	}
+	feed, err := NewFeed(c.Title, description, channelLink, parser.ResolveURL(base, c.Image), items...)
 	if err != nil {
 		return nil, err
 	}
+	feed.Authors = newAuthors(c.Authors, base)
+	feed.Schedule = newSchedule(c)
+	feed.Categories = newCategories(c.Categories)
+	feed.Report = report
 	return feed, nil
 }
 
 type Feed struct {
-	ID          int64   `db:"id" json:"id"`
-	Title       string  `db:"title" json:"title"`
-	Description string  `db:"description" json:"description"`
-	Link        string  `db:"link" json:"link"`
-	Image       string  `db:"image" json:"image"`
-	Items       []*Item `db:"-" json:"items"`
+	ID          int64       `db:"id" json:"id"`
+	Title       string      `db:"title" json:"title"`
+	Description string      `db:"description" json:"description"`
+	Link        string      `db:"link" json:"link"`
+	Image       string      `db:"image" json:"image"`
+	Items       []*Item     `db:"-" json:"items"`
+	Authors     []*Author   `db:"-" json:"authors"`
+	Categories  []*Category `db:"-" json:"categories"`
+
+	// URL is the address the feed is fetched from, as opposed to Link which
+	// points at the website the feed belongs to. ETag and LastModified are
+	// the HTTP validators from the last fetch, FetchedAt is when it was made
+	// and NextFetch is the earliest time the publisher asked us to fetch
+	// again.
+	URL          string     `db:"url" json:"url"`
+	ETag         string     `db:"etag" json:"-"`
+	LastModified string     `db:"last_modified" json:"-"`
+	FetchedAt    *time.Time `db:"fetched_at" json:"fetchedAt,omitempty"`
+	NextFetch    time.Time  `db:"next_fetch" json:"nextFetch"`
+
+	// Schedule holds the publisher's hints about how often to fetch the
+	// feed, which EarliestNextFetch combines with NextFetch.
+	Schedule Schedule `db:"schedule" json:"schedule"`
+
+	// Report lists the problems found in the feed when it was last fetched.
+	// It is served on its own rather than with the feed.
+	Report Report `db:"report" json:"-"`
+
+	// Aliases are addresses the feed used to be fetched from before it
+	// moved, and MovedAt is when it last moved. Subscribing to an alias
+	// finds the existing feed rather than creating a duplicate.
+	Aliases []string   `db:"-" json:"aliases"`
+	MovedAt *time.Time `db:"moved_at" json:"movedAt,omitempty"`
+
+	// RejectedSelfLink is the last self link of the feed that turned out
+	// not to publish the same feed, so that it is not checked again on
+	// every fetch.
+	RejectedSelfLink string `db:"rejected_self_link" json:"-"`
+}
+
+// EarliestNextFetch returns the earliest time the feed should be fetched again
+// after a fetch at last, respecting both the HTTP caching headers recorded in
+// NextFetch and the publisher's schedule.
+func (f *Feed) EarliestNextFetch(last time.Time) time.Time {
+	next := f.Schedule.NextFetch(last)
+	if f.NextFetch.After(next) {
+		return f.NextFetch
+	}
+	return next
 }
 
 func NewItem(feed int64, title, link string, pub time.Time) (*Item, error) {
@@ -108,12 +255,22 @@ func NewItem(feed int64, title, link string, pub time.Time) (*Item, error) {
 }
 
 type Item struct {
-	ID              int64     `db:"id" json:"id"`
-	FeedID          int64     `db:"feed_id" json:"feedID"`
-	Title           string    `db:"title" json:"title"`
-	Link            string    `db:"link" json:"link"`
-	PublicationDate time.Time `db:"publication_date" json:"publicationDate"`
-	Read            bool      `db:"read" json:"read"`
-	Starred         bool      `db:"starred" json:"starred"`
-	Ignored         bool      `db:"ignored" json:"ignored"`
+	ID              int64        `db:"id" json:"id"`
+	FeedID          int64        `db:"feed_id" json:"feedID"`
+	GUID            string       `db:"guid" json:"guid"`
+	Title           string       `db:"title" json:"title"`
+	Link            string       `db:"link" json:"link"`
+	PublicationDate time.Time    `db:"publication_date" json:"publicationDate"`
+	Summary         string       `db:"summary" json:"summary"`
+	Content         string       `db:"content" json:"content"`
+	Authors         []*Author    `db:"-" json:"authors"`
+	Categories      []*Category  `db:"-" json:"categories"`
+	Enclosures      []*Enclosure `db:"-" json:"enclosures"`
+	Media           Media        `db:"media" json:"media"`
+	LeadImage       string       `db:"lead_image" json:"leadImage"`
+	Read            bool         `db:"read" json:"read"`
+	Starred         bool         `db:"starred" json:"starred"`
+	Ignored         bool         `db:"ignored" json:"ignored"`
+
+	Podcast `json:"podcast"`
 }
@@ -0,0 +1,132 @@
+package rss_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromChannelAtomWithoutSubtitle(t *testing.T) {
+	const input = `<feed xmlns="http://www.w3.org/2005/Atom"><title>Example</title><link href="https://example.com/"/>` +
+		`<entry><title>A</title><link href="https://example.com/a"/><updated>2019-04-01T10:00:00Z</updated></entry></feed>`
+
+	parsed, err := parser.Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed, err := rss.NewFromChannel(parsed.Channel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Description != "Example" {
+		t.Errorf("expected description %q, got %q", "Example", feed.Description)
+	}
+	if len(feed.Items) != 1 {
+		t.Errorf("expected %d item, got %d", 1, len(feed.Items))
+	}
+}
+
+func TestNewFromChannelRelativeURLs(t *testing.T) {
+	const item = `<item>
+		<title>Post</title>
+		<link>posts/1</link>
+		<pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate>
+		<enclosure url="audio/1.mp3" type="audio/mpeg" length="1"/>
+		<itunes:image href="/art/1.jpg"/>
+		<media:thumbnail url="thumbs/1.jpg"/>
+		<description><![CDATA[<a href="../about">About</a> <img src="img/1.png">]]></description>
+	</item>`
+
+	testcases := []struct {
+		name      string
+		base      string
+		channel   string
+		link      string
+		enclosure string
+		image     string
+		thumbnail string
+		content   string
+		feedLink  string
+		feedImage string
+	}{
+		{
+			name:      "channel link",
+			channel:   `<channel><title>T</title><description>D</description><link>http://example.com/blog/</link><image><url>logo.png</url></image>` + item + `</channel>`,
+			link:      "http://example.com/blog/posts/1",
+			enclosure: "http://example.com/blog/audio/1.mp3",
+			image:     "http://example.com/art/1.jpg",
+			thumbnail: "http://example.com/blog/thumbs/1.jpg",
+			content:   `<a href="http://example.com/about">About</a> <img src="http://example.com/blog/img/1.png">`,
+			feedLink:  "http://example.com/blog/",
+			feedImage: "http://example.com/blog/logo.png",
+		},
+		{
+			name:      "fetch url",
+			base:      "http://feeds.example.com/blog/rss",
+			channel:   `<channel><title>T</title><description>D</description><link>/</link>` + item + `</channel>`,
+			link:      "http://feeds.example.com/blog/posts/1",
+			enclosure: "http://feeds.example.com/blog/audio/1.mp3",
+			image:     "http://feeds.example.com/art/1.jpg",
+			thumbnail: "http://feeds.example.com/blog/thumbs/1.jpg",
+			content:   `<a href="http://feeds.example.com/about">About</a> <img src="http://feeds.example.com/blog/img/1.png">`,
+			feedLink:  "http://feeds.example.com/",
+		},
+		{
+			name:      "item xml:base",
+			base:      "http://feeds.example.com/blog/rss",
+			channel:   `<channel><title>T</title><description>D</description><link>http://example.com/</link>` + strings.Replace(item, "<item>", `<item xml:base="http://cdn.example.com/2019/">`, 1) + `</channel>`,
+			link:      "http://cdn.example.com/2019/posts/1",
+			enclosure: "http://cdn.example.com/2019/audio/1.mp3",
+			image:     "http://cdn.example.com/art/1.jpg",
+			thumbnail: "http://cdn.example.com/2019/thumbs/1.jpg",
+			content:   `<a href="http://cdn.example.com/about">About</a> <img src="http://cdn.example.com/2019/img/1.png">`,
+			feedLink:  "http://example.com/",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">` + tc.channel + `</rss>`
+			xmlFeed, err := parser.Load(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.base != "" {
+				xmlFeed.Channel.Base = tc.base
+			}
+
+			feed, err := rss.NewFromChannel(xmlFeed.Channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if feed.Link != tc.feedLink {
+				t.Errorf("expected feed link %q, got %q", tc.feedLink, feed.Link)
+			}
+			if feed.Image != tc.feedImage {
+				t.Errorf("expected feed image %q, got %q", tc.feedImage, feed.Image)
+			}
+			if len(feed.Items) != 1 {
+				t.Fatalf("expected 1 item, got %d", len(feed.Items))
+			}
+
+			got := feed.Items[0]
+			if got.Link != tc.link {
+				t.Errorf("expected link %q, got %q", tc.link, got.Link)
+			}
+			if len(got.Enclosures) != 1 || got.Enclosures[0].URL != tc.enclosure {
+				t.Errorf("expected enclosure %q, got %v", tc.enclosure, got.Enclosures)
+			}
+			if got.Podcast.Image != tc.image {
+				t.Errorf("expected image %q, got %q", tc.image, got.Podcast.Image)
+			}
+			if len(got.Media.Thumbnails) != 1 || got.Media.Thumbnails[0].URL != tc.thumbnail {
+				t.Errorf("expected thumbnail %q, got %v", tc.thumbnail, got.Media.Thumbnails)
+			}
+			if got.Summary != tc.content {
+				t.Errorf("expected summary %q, got %q", tc.content, got.Summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Package rsscloud registers for rssCloud notifications, which some older
+// feeds offer instead of WebSub. A cloud does not deliver content; it pings us
+// with the address of a feed that has changed, and the feed is then fetched as
+// usual.
+//
+// Only the http-post protocol is supported. Clouds forget registrations after
+// 25 hours, so Run should be left running to renew them every day.
+package rsscloud
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+// RegistrationInterval is how often registrations are renewed. The
+// specification has clouds drop registrations that are more than 25 hours
+// old.
+const RegistrationInterval = 24 * time.Hour
+
+// DefaultTimeout is used by NewSubscriber if Config.Timeout is not set.
+const DefaultTimeout = parser.DefaultTimeout
+
+// ErrUnknownFeed is returned by CheckPing for pings about feeds we did not
+// register for.
+var ErrUnknownFeed = errors.New("not registered for notifications about this feed")
+
+// Config controls how a Subscriber talks to clouds.
+type Config struct {
+	// CallbackURL is the public address that the Subscriber is served at,
+	// such as http://reader.example.com/rsscloud. Clouds notify us at this
+	// address followed by the ID of the feed. Clouds are only told the
+	// domain, port and path, and always notify over plain HTTP.
+	CallbackURL string
+
+	// Timeout bounds each request to a cloud.
+	Timeout time.Duration
+}
+
+// Subscriber manages rssCloud registrations for feeds. It is an http.Handler
+// answering the challenges clouds send to check that a registration was
+// really made by us, at paths ending in the feed's ID. Pings themselves are
+// left to the caller, who should check them with CheckPing and refresh the
+// feed.
+type Subscriber struct {
+	repository rss.Repository
+	client     *http.Client
+	domain     string
+	port       string
+	path       string
+}
+
+func NewSubscriber(repo rss.Repository, config Config) (*Subscriber, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	callback, err := url.Parse(config.CallbackURL)
+	if err != nil {
+		return nil, err
+	}
+	if callback.Hostname() == "" {
+		return nil, errors.Errorf("callback URL %q has no host", config.CallbackURL)
+	}
+	port := callback.Port()
+	if port == "" {
+		port = "80"
+	}
+	return &Subscriber{
+		repository: repo,
+		client:     &http.Client{Timeout: config.Timeout},
+		domain:     callback.Hostname(),
+		port:       port,
+		path:       strings.TrimRight(callback.Path, "/"),
+	}, nil
+}
+
+// RegisterChannel registers for notifications about a feed, published at url,
+// with the cloud its channel names. Nothing is done if the channel has no
+// cloud we can talk to, or the feed was registered with the same cloud less
+// than a day ago.
+func (s *Subscriber) RegisterChannel(ctx context.Context, feedID int64, c parser.Channel, url string) error {
+	endpoint := c.Cloud.RegisterURL()
+	if endpoint == "" {
+		return nil
+	}
+	existing, err := s.repository.GetCloudRegistration(feedID)
+	if err != nil && err != rss.ErrRegistrationNotFound {
+		return err
+	}
+	if err == nil && existing.Endpoint == endpoint && existing.Topic == url &&
+		existing.RegisteredAt != nil && time.Since(*existing.RegisteredAt) < RegistrationInterval {
+		return nil
+	}
+	return s.Register(ctx, feedID, endpoint, url)
+}
+
+// Register asks the cloud at endpoint to notify us when the feed published at
+// topic changes. If the cloud can not be reached, or refuses, the feed is left
+// with the registration it had before, if any: a renewal that fails keeps the
+// existing registration and the time it was made, so that it is tried again
+// later, while a first registration is dropped.
+func (s *Subscriber) Register(ctx context.Context, feedID int64, endpoint, topic string) error {
+	previous, err := s.repository.GetCloudRegistration(feedID)
+	if err != nil && err != rss.ErrRegistrationNotFound {
+		return err
+	}
+	reg := &rss.CloudRegistration{
+		FeedID:   feedID,
+		Endpoint: endpoint,
+		Topic:    topic,
+	}
+	if previous != nil && previous.Endpoint == endpoint && previous.Topic == topic {
+		reg.RegisteredAt = previous.RegisteredAt
+	}
+	// The cloud checks the registration with a challenge before it
+	// replies, so it has to be stored first.
+	if err := s.repository.SaveCloudRegistration(reg); err != nil {
+		return err
+	}
+	if err := s.request(ctx, reg); err != nil {
+		var rerr error
+		if previous != nil {
+			rerr = s.repository.SaveCloudRegistration(previous)
+		} else {
+			rerr = s.repository.RemoveCloudRegistration(feedID)
+		}
+		if rerr != nil {
+			log.Printf("error restoring cloud registration for %s: %v\n", topic, rerr)
+		}
+		return err
+	}
+	now := time.Now()
+	reg.RegisteredAt = &now
+	return s.repository.SaveCloudRegistration(reg)
+}
+
+// Unregister stops accepting notifications about a feed. rssCloud has no way
+// to cancel a registration, so the cloud keeps pinging us until it lapses.
+func (s *Subscriber) Unregister(feedID int64) error {
+	return s.repository.RemoveCloudRegistration(feedID)
+}
+
+// Renew registers again every registration made more than
+// RegistrationInterval before now. A cloud that fails to renew a registration
+// does not stop the others from being renewed; the failure is logged.
+func (s *Subscriber) Renew(ctx context.Context, now time.Time) error {
+	regs, err := s.repository.ListCloudRegistrationsBefore(now.Add(-RegistrationInterval))
+	if err != nil {
+		return err
+	}
+	for _, reg := range regs {
+		if err := s.Register(ctx, reg.FeedID, reg.Endpoint, reg.Topic); err != nil {
+			log.Printf("error renewing cloud registration for %s: %v\n", reg.Topic, err)
+		}
+	}
+	return nil
+}
+
+// Run renews registrations every interval until ctx is cancelled. The
+// interval should be well under a day, so that registrations are renewed
+// before clouds drop them.
+func (s *Subscriber) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Renew(ctx, time.Now()); err != nil {
+			log.Printf("error renewing cloud registrations: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckPing returns an error unless a ping about topic on the callback for a
+// feed matches a registration we made.
+func (s *Subscriber) CheckPing(feedID int64, topic string) error {
+	reg, err := s.repository.GetCloudRegistration(feedID)
+	if err == rss.ErrRegistrationNotFound {
+		return ErrUnknownFeed
+	}
+	if err != nil {
+		return err
+	}
+	if reg.Topic != topic {
+		return ErrUnknownFeed
+	}
+	return nil
+}
+
+// notifyResult is the reply of a cloud to a registration.
+type notifyResult struct {
+	Success string `xml:"success,attr"`
+	Message string `xml:"msg,attr"`
+}
+
+// request sends a registration request to the cloud of reg.
+func (s *Subscriber) request(ctx context.Context, reg *rss.CloudRegistration) error {
+	form := url.Values{
+		"notifyProcedure": {""},
+		"domain":          {s.domain},
+		"port":            {s.port},
+		"path":            {s.callbackPath(reg.FeedID)},
+		"protocol":        {"http-post"},
+		"url1":            {reg.Topic},
+	}
+	req, err := http.NewRequest(http.MethodPost, reg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", parser.DefaultUserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("cloud %s responded with status %d", reg.Endpoint, resp.StatusCode)
+	}
+	var result notifyResult
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result); err != nil {
+		return errors.Wrapf(err, "cloud %s sent an invalid reply", reg.Endpoint)
+	}
+	if !strings.EqualFold(result.Success, "true") {
+		return errors.Errorf("cloud %s refused registration: %s", reg.Endpoint, result.Message)
+	}
+	return nil
+}
+
+func (s *Subscriber) callbackPath(feedID int64) string {
+	return s.path + "/" + strconv.FormatInt(feedID, 10)
+}
+
+// ServeHTTP answers the challenge a cloud sends to the callback of a feed to
+// check that we asked for the registration, by echoing it back.
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	feedID, err := strconv.ParseInt(path.Base(r.URL.Path), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	challenge := q.Get("challenge")
+	if err := s.CheckPing(feedID, q.Get("url")); err != nil || challenge == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, challenge)
+}
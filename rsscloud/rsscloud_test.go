@@ -0,0 +1,186 @@
+package rsscloud_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/mock"
+	"github.com/haleyrc/rss/rsscloud"
+)
+
+const (
+	feedID   = 1
+	topic    = "https://example.com/feed.xml"
+	callback = "http://reader.example.com:8080/rsscloud"
+)
+
+const (
+	accepted = `<?xml version="1.0"?><notifyResult success="true" msg="Thanks for the registration."/>`
+	refused  = `<?xml version="1.0"?><notifyResult success="false" msg="The subscription was cancelled because the call failed when we tested the handler."/>`
+)
+
+func TestRegister(t *testing.T) {
+	testcases := []struct {
+		name   string
+		status int
+		reply  string
+		err    bool
+	}{
+		{name: "accepted", status: http.StatusOK, reply: accepted},
+		{name: "refused", status: http.StatusOK, reply: refused, err: true},
+		{name: "failing", status: http.StatusInternalServerError, err: true},
+		{name: "invalid reply", status: http.StatusOK, reply: "OK", err: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mock.NewRepository()
+			subscriber, err := rsscloud.NewSubscriber(repo, rsscloud.Config{CallbackURL: callback})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var form url.Values
+			cloud := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				form = r.PostForm
+				// The cloud checks the registration before replying, so
+				// it must already be stored.
+				if _, err := repo.GetCloudRegistration(feedID); err != nil {
+					t.Errorf("expected registration to be stored before replying, got %v", err)
+				}
+				w.WriteHeader(tc.status)
+				io.WriteString(w, tc.reply)
+			}))
+			defer cloud.Close()
+
+			err = subscriber.Register(context.Background(), feedID, cloud.URL, topic)
+			want := url.Values{
+				"notifyProcedure": {""},
+				"domain":          {"reader.example.com"},
+				"port":            {"8080"},
+				"path":            {"/rsscloud/1"},
+				"protocol":        {"http-post"},
+				"url1":            {topic},
+			}
+			for key := range want {
+				if form.Get(key) != want.Get(key) {
+					t.Errorf("expected %s %q, got %q", key, want.Get(key), form.Get(key))
+				}
+			}
+
+			reg, rerr := repo.GetCloudRegistration(feedID)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				if rerr != rss.ErrRegistrationNotFound {
+					t.Errorf("expected error %v, got %v", rss.ErrRegistrationNotFound, rerr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rerr != nil {
+				t.Fatalf("unexpected error: %v", rerr)
+			}
+			if reg.RegisteredAt == nil || time.Since(*reg.RegisteredAt) > time.Minute {
+				t.Errorf("expected registration to be recorded now, got %v", reg.RegisteredAt)
+			}
+		})
+	}
+}
+
+func TestRenew(t *testing.T) {
+	testcases := []struct {
+		name    string
+		age     time.Duration
+		status  int
+		renewed bool
+	}{
+		{"a day old", 24*time.Hour + time.Minute, http.StatusOK, true},
+		{"recent", time.Hour, http.StatusOK, false},
+		{"cloud failing", 24*time.Hour + time.Minute, http.StatusInternalServerError, false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cloud := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				io.WriteString(w, accepted)
+			}))
+			defer cloud.Close()
+
+			repo := mock.NewRepository()
+			registeredAt := time.Now().Add(-tc.age)
+			if err := repo.SaveCloudRegistration(&rss.CloudRegistration{
+				FeedID:       feedID,
+				Endpoint:     cloud.URL,
+				Topic:        topic,
+				RegisteredAt: &registeredAt,
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			subscriber, err := rsscloud.NewSubscriber(repo, rsscloud.Config{CallbackURL: callback})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := subscriber.Renew(context.Background(), time.Now()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reg, err := repo.GetCloudRegistration(feedID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if renewed := reg.RegisteredAt.After(registeredAt); renewed != tc.renewed {
+				t.Errorf("expected renewed to be %t, got registration made at %v", tc.renewed, reg.RegisteredAt)
+			}
+		})
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	testcases := []struct {
+		name   string
+		method string
+		target string
+		status int
+		body   string
+	}{
+		{"challenge", http.MethodGet, "/rsscloud/1?url=" + url.QueryEscape(topic) + "&challenge=a1b2c3", http.StatusOK, "a1b2c3"},
+		{"other topic", http.MethodGet, "/rsscloud/1?url=https://example.com/other.xml&challenge=a1b2c3", http.StatusNotFound, ""},
+		{"other feed", http.MethodGet, "/rsscloud/2?url=" + url.QueryEscape(topic) + "&challenge=a1b2c3", http.StatusNotFound, ""},
+		{"no challenge", http.MethodGet, "/rsscloud/1?url=" + url.QueryEscape(topic), http.StatusNotFound, ""},
+		{"post", http.MethodPost, "/rsscloud/1?url=" + url.QueryEscape(topic) + "&challenge=a1b2c3", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mock.NewRepository()
+			if err := repo.SaveCloudRegistration(&rss.CloudRegistration{FeedID: feedID, Topic: topic}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			subscriber, err := rsscloud.NewSubscriber(repo, rsscloud.Config{CallbackURL: callback})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			subscriber.ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, nil))
+			if w.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, w.Code)
+			}
+			if tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, w.Body.String())
+			}
+		})
+	}
+}
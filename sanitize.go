@@ -0,0 +1,314 @@
+package rss
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss/markup"
+)
+
+// Policy is an allow-list of the HTML elements and attributes that may appear
+// in item content. Anything not on the list is removed, although the text
+// inside a removed element is kept unless the element is one whose content is
+// never meant to be displayed, such as <script>.
+type Policy struct {
+	Name     string
+	elements map[string][]string
+}
+
+var (
+	// StrictPolicy allows basic text formatting and links only. It is
+	// suitable for places where content is shown inline, such as item lists.
+	StrictPolicy = &Policy{
+		Name: "strict",
+		elements: map[string][]string{
+			"a":          {"href", "title"},
+			"b":          nil,
+			"blockquote": {"cite"},
+			"br":         nil,
+			"code":       nil,
+			"em":         nil,
+			"i":          nil,
+			"li":         nil,
+			"ol":         nil,
+			"p":          nil,
+			"pre":        nil,
+			"q":          {"cite"},
+			"s":          nil,
+			"strong":     nil,
+			"sub":        nil,
+			"sup":        nil,
+			"u":          nil,
+			"ul":         nil,
+		},
+	}
+
+	// ReadingPolicy extends StrictPolicy with the structure and media that
+	// make up a typical article. This is what we apply to items by default.
+	ReadingPolicy = &Policy{
+		Name: "reading",
+		elements: merge(StrictPolicy.elements, map[string][]string{
+			"abbr":       {"title"},
+			"article":    nil,
+			"aside":      nil,
+			"audio":      {"src", "controls"},
+			"caption":    nil,
+			"cite":       nil,
+			"dd":         nil,
+			"del":        nil,
+			"div":        nil,
+			"dl":         nil,
+			"dt":         nil,
+			"figcaption": nil,
+			"figure":     nil,
+			"h1":         nil,
+			"h2":         nil,
+			"h3":         nil,
+			"h4":         nil,
+			"h5":         nil,
+			"h6":         nil,
+			"hr":         nil,
+			"img":        {"src", "alt", "title", "width", "height"},
+			"ins":        nil,
+			"mark":       nil,
+			"section":    nil,
+			"small":      nil,
+			"source":     {"src", "type"},
+			"span":       nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"colspan", "rowspan"},
+			"tfoot":      nil,
+			"th":         {"colspan", "rowspan", "scope"},
+			"thead":      nil,
+			"time":       {"datetime"},
+			"tr":         nil,
+			"video":      {"src", "controls", "poster", "width", "height"},
+		}),
+	}
+
+	// RawPolicy leaves content untouched. It exists for trusted feeds and
+	// for debugging and should never be used for content shown to users.
+	RawPolicy = &Policy{Name: "raw"}
+)
+
+// policies are the named policies that can be looked up with PolicyByName.
+var policies = map[string]*Policy{
+	StrictPolicy.Name:  StrictPolicy,
+	ReadingPolicy.Name: ReadingPolicy,
+	RawPolicy.Name:     RawPolicy,
+}
+
+// PolicyByName returns the policy with the given name.
+func PolicyByName(name string) (*Policy, error) {
+	p, ok := policies[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, errors.Errorf("unknown sanitisation policy %q", name)
+	}
+	return p, nil
+}
+
+// droppedElements are removed along with everything inside them.
+var droppedElements = map[string]bool{
+	"embed":    true,
+	"frame":    true,
+	"frameset": true,
+	"iframe":   true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"title":    true,
+}
+
+// urlAttributes hold URLs which are resolved against the item link and checked
+// for dangerous schemes.
+var urlAttributes = map[string]bool{
+	"cite":   true,
+	"href":   true,
+	"poster": true,
+	"src":    true,
+}
+
+var allowedSchemes = map[string]bool{
+	"":       true,
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// trackingHosts serve invisible images whose only purpose is to record that
+// an item was read. Images from these hosts are removed regardless of size.
+var trackingHosts = []string{
+	"feeds.feedburner.com",
+	"pixel.wp.com",
+	"stats.wordpress.com",
+	"pixel.quantserve.com",
+	"www.google-analytics.com",
+}
+
+// Sanitize removes everything from input that is not allowed by the policy,
+// including tracking pixels, and resolves relative URLs against base, which is
+// normally the link of the item the content belongs to.
+func (p *Policy) Sanitize(input, base string) string {
+	if p.elements == nil {
+		return input
+	}
+
+	baseURL, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		baseURL = nil
+	}
+
+	var out strings.Builder
+	dropping := 0
+	for _, tok := range markup.Tokenize(input) {
+		switch tok.Type {
+		case markup.TextToken:
+			if dropping == 0 {
+				out.WriteString(tok.String())
+			}
+		case markup.StartTagToken, markup.SelfClosingTagToken:
+			if droppedElements[tok.Data] {
+				if tok.Type == markup.StartTagToken && !isVoid(tok.Data) {
+					dropping++
+				}
+				continue
+			}
+			if dropping > 0 {
+				continue
+			}
+			clean, ok := p.cleanTag(tok, baseURL)
+			if !ok {
+				continue
+			}
+			out.WriteString(clean.String())
+		case markup.EndTagToken:
+			if droppedElements[tok.Data] {
+				if dropping > 0 {
+					dropping--
+				}
+				continue
+			}
+			if dropping > 0 || isVoid(tok.Data) {
+				continue
+			}
+			if _, ok := p.elements[tok.Data]; ok {
+				out.WriteString(tok.String())
+			}
+		}
+	}
+	return out.String()
+}
+
+// cleanTag strips any attributes not allowed by the policy from tok. It
+// returns false if the element should be removed entirely.
+func (p *Policy) cleanTag(tok markup.Token, base *url.URL) (markup.Token, bool) {
+	allowed, ok := p.elements[tok.Data]
+	if !ok {
+		return tok, false
+	}
+	if tok.Data == "img" && isTrackingPixel(tok) {
+		return tok, false
+	}
+
+	clean := markup.Token{Type: markup.StartTagToken, Data: tok.Data}
+	for _, attr := range tok.Attr {
+		if !contains(allowed, attr.Key) {
+			continue
+		}
+		if urlAttributes[attr.Key] {
+			u, ok := cleanURL(attr.Val, base)
+			if !ok {
+				continue
+			}
+			attr.Val = u
+		}
+		clean.Attr = append(clean.Attr, attr)
+	}
+	if tok.Data == "img" {
+		if _, ok := clean.Get("src"); !ok {
+			return tok, false
+		}
+	}
+	return clean, true
+}
+
+// cleanURL resolves raw against base and reports whether the result uses a
+// scheme that is safe to link to.
+func cleanURL(raw string, base *url.URL) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	if base != nil && !u.IsAbs() {
+		u = base.ResolveReference(u)
+	}
+	return u.String(), true
+}
+
+func isTrackingPixel(tok markup.Token) bool {
+	width, _ := tok.Get("width")
+	height, _ := tok.Get("height")
+	if isTiny(width) && isTiny(height) {
+		return true
+	}
+	src, _ := tok.Get("src")
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil {
+		return false
+	}
+	for _, host := range trackingHosts {
+		if strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTiny reports whether an image dimension is zero or one pixel.
+func isTiny(dimension string) bool {
+	dimension = strings.TrimSuffix(strings.TrimSpace(dimension), "px")
+	n, err := strconv.Atoi(dimension)
+	return err == nil && n <= 1
+}
+
+// isVoid reports whether name is an element that has no end tag. Void members
+// of droppedElements must be listed here, or everything after them would be
+// dropped waiting for an end tag that never comes.
+func isVoid(name string) bool {
+	switch name {
+	case "area", "base", "br", "col", "embed", "frame", "hr", "img", "input",
+		"link", "meta", "param", "source", "track", "wbr":
+		return true
+	default:
+		return false
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func merge(maps ...map[string][]string) map[string][]string {
+	merged := make(map[string][]string)
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,115 @@
+package rss_test
+
+import (
+	"testing"
+
+	"github.com/haleyrc/rss"
+)
+
+func TestSanitize(t *testing.T) {
+	const base = "https://example.com/posts/hello"
+
+	testcases := []struct {
+		name   string
+		policy *rss.Policy
+		input  string
+		want   string
+	}{
+		{
+			name:   "script removed with content",
+			policy: rss.ReadingPolicy,
+			input:  `<p>Hello<script>alert("hi")</script></p>`,
+			want:   `<p>Hello</p>`,
+		},
+		{
+			name:   "iframe removed with content",
+			policy: rss.ReadingPolicy,
+			input:  `<iframe src="https://evil.example.com">fallback</iframe><p>text</p>`,
+			want:   `<p>text</p>`,
+		},
+		{
+			name:   "frame removed without its end tag",
+			policy: rss.ReadingPolicy,
+			input:  `<frame src="https://evil.example.com"><p>text</p>`,
+			want:   `<p>text</p>`,
+		},
+		{
+			name:   "event handlers removed",
+			policy: rss.ReadingPolicy,
+			input:  `<p onclick="steal()" style="color:red">text</p>`,
+			want:   `<p>text</p>`,
+		},
+		{
+			name:   "javascript links removed",
+			policy: rss.ReadingPolicy,
+			input:  `<a href="javascript:alert(1)">click</a>`,
+			want:   `<a>click</a>`,
+		},
+		{
+			name:   "unknown elements unwrapped",
+			policy: rss.ReadingPolicy,
+			input:  `<form><input type="text">Name</form>`,
+			want:   `Name`,
+		},
+		{
+			name:   "relative urls resolved",
+			policy: rss.ReadingPolicy,
+			input:  `<a href="../about">About</a><img src="/images/a.png" alt="A">`,
+			want:   `<a href="https://example.com/about">About</a><img src="https://example.com/images/a.png" alt="A">`,
+		},
+		{
+			name:   "tracking pixel removed",
+			policy: rss.ReadingPolicy,
+			input:  `<p>text</p><img src="https://example.com/t.gif" width="1" height="1">`,
+			want:   `<p>text</p>`,
+		},
+		{
+			name:   "tracking host removed",
+			policy: rss.ReadingPolicy,
+			input:  `<img src="https://feeds.feedburner.com/~r/example/~4/abc">`,
+			want:   ``,
+		},
+		{
+			name:   "text escaped",
+			policy: rss.ReadingPolicy,
+			input:  `fish &amp; chips &lt;3`,
+			want:   `fish &amp; chips &lt;3`,
+		},
+		{
+			name:   "strict drops images",
+			policy: rss.StrictPolicy,
+			input:  `<h2>Title</h2><img src="https://example.com/a.png"><p><em>text</em></p>`,
+			want:   `Title<p><em>text</em></p>`,
+		},
+		{
+			name:   "raw leaves content alone",
+			policy: rss.RawPolicy,
+			input:  `<p onclick="x()">text<script>y()</script></p>`,
+			want:   `<p onclick="x()">text<script>y()</script></p>`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.policy.Sanitize(tc.input, base)
+			if got != tc.want {
+				t.Errorf("wanted %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPolicyByName(t *testing.T) {
+	for _, name := range []string{"strict", "reading", "raw"} {
+		p, err := rss.PolicyByName(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.Name != name {
+			t.Errorf("expected policy %q, got %q", name, p.Name)
+		}
+	}
+	if _, err := rss.PolicyByName("permissive"); err == nil {
+		t.Errorf("expected error, but got none")
+	}
+}
@@ -0,0 +1,159 @@
+package rss
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss/parser"
+)
+
+// MaxScheduleInterval caps how long a publisher can ask us to wait between
+// fetches. Feeds that claim to change once a year are still checked daily.
+const MaxScheduleInterval = 24 * time.Hour
+
+// updatePeriods are the periods allowed by the syndication module.
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// Schedule holds the hints a publisher gives about how often a feed should be
+// fetched. TTL is in minutes. SkipHours are hours of the day, in GMT, and
+// SkipDays are days of the week during which the feed should not be fetched.
+// UpdatePeriod and UpdateFrequency come from the syndication module and mean
+// that the feed changes UpdateFrequency times every UpdatePeriod.
+type Schedule struct {
+	TTL             int      `json:"ttl,omitempty"`
+	SkipHours       []int    `json:"skipHours,omitempty"`
+	SkipDays        []string `json:"skipDays,omitempty"`
+	UpdatePeriod    string   `json:"updatePeriod,omitempty"`
+	UpdateFrequency int      `json:"updateFrequency,omitempty"`
+}
+
+// Value stores a Schedule as JSON so that it can be kept in a single column.
+func (s Schedule) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan loads a Schedule stored by Value.
+func (s *Schedule) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = Schedule{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return errors.Errorf("can not scan %T into Schedule", src)
+	}
+}
+
+// Interval returns the shortest time the publisher would like us to leave
+// between fetches, which is the longer of the TTL and the update period
+// divided by its frequency.
+func (s Schedule) Interval() time.Duration {
+	// The TTL is capped before it is converted, as a large enough one would
+	// overflow a Duration.
+	interval := MaxScheduleInterval
+	if ttl := time.Duration(s.TTL); ttl < MaxScheduleInterval/time.Minute {
+		interval = ttl * time.Minute
+	}
+	if period, ok := updatePeriods[s.UpdatePeriod]; ok {
+		frequency := s.UpdateFrequency
+		if frequency < 1 {
+			frequency = 1
+		}
+		if d := period / time.Duration(frequency); d > interval {
+			interval = d
+		}
+	}
+	if interval > MaxScheduleInterval {
+		interval = MaxScheduleInterval
+	}
+	return interval
+}
+
+// NextFetch returns the earliest time after a fetch at last that the feed
+// should be fetched again: once the interval has passed, and outside of the
+// hours and days that the publisher asked us to skip. If every hour or every
+// day is skipped the skips are ignored, as the feed could never be fetched.
+func (s Schedule) NextFetch(last time.Time) time.Time {
+	next := last.Add(s.Interval())
+	if len(s.SkipHours) >= 24 || len(s.SkipDays) >= 7 {
+		return next
+	}
+	for i := 0; i < 7*24 && s.skips(next); i++ {
+		next = next.UTC().Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+func (s Schedule) skips(t time.Time) bool {
+	t = t.UTC()
+	for _, h := range s.SkipHours {
+		if h == t.Hour() {
+			return true
+		}
+	}
+	for _, d := range s.SkipDays {
+		if d == t.Weekday().String() {
+			return true
+		}
+	}
+	return false
+}
+
+// newSchedule reads the publishing hints of a channel, ignoring any that are
+// malformed.
+func newSchedule(c parser.Channel) Schedule {
+	var s Schedule
+	if ttl, err := strconv.Atoi(strings.TrimSpace(c.TTL)); err == nil && ttl > 0 {
+		s.TTL = ttl
+	}
+
+	// RSS numbers hours from 0 to 23, but some publishers use 24 for
+	// midnight.
+	seen := make(map[int]bool)
+	for _, hour := range c.SkipHours {
+		h, err := strconv.Atoi(strings.TrimSpace(hour))
+		if err != nil || h < 0 || h > 24 {
+			continue
+		}
+		h %= 24
+		if !seen[h] {
+			seen[h] = true
+			s.SkipHours = append(s.SkipHours, h)
+		}
+	}
+	sort.Ints(s.SkipHours)
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		for _, day := range c.SkipDays {
+			if strings.EqualFold(strings.TrimSpace(day), d.String()) {
+				s.SkipDays = append(s.SkipDays, d.String())
+				break
+			}
+		}
+	}
+
+	period := strings.ToLower(strings.TrimSpace(c.UpdatePeriod))
+	if _, ok := updatePeriods[period]; ok {
+		s.UpdatePeriod = period
+		s.UpdateFrequency = 1
+		if f, err := strconv.Atoi(strings.TrimSpace(c.UpdateFrequency)); err == nil && f > 0 {
+			s.UpdateFrequency = f
+		}
+	}
+	return s
+}
@@ -0,0 +1,121 @@
+package rss_test
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+func TestNewFromChannelSchedule(t *testing.T) {
+	testcases := []struct {
+		name  string
+		input string
+		want  rss.Schedule
+	}{
+		{
+			name:  "rss",
+			input: `<rss version="2.0"><channel><ttl> 60 </ttl><skipHours><hour>3</hour><hour>1</hour><hour>24</hour><hour>1</hour><hour>25</hour></skipHours><skipDays><day>saturday</day><day>Sunday</day><day>Someday</day></skipDays>`,
+			want:  rss.Schedule{TTL: 60, SkipHours: []int{0, 1, 3}, SkipDays: []string{"Sunday", "Saturday"}},
+		},
+		{
+			name:  "syndication module",
+			input: `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel><sy:updatePeriod>Hourly</sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency>`,
+			want:  rss.Schedule{UpdatePeriod: "hourly", UpdateFrequency: 2},
+		},
+		{
+			name:  "malformed",
+			input: `<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"><channel><ttl>soon</ttl><sy:updatePeriod>fortnightly</sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency>`,
+			want:  rss.Schedule{},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := tc.input + `<title>T</title><description>D</description><link>http://example.com/</link></channel></rss>`
+			xmlFeed, err := parser.Load(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			feed, err := rss.NewFromChannel(xmlFeed.Channel)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(feed.Schedule, tc.want) {
+				t.Errorf("expected schedule %+v, got %+v", tc.want, feed.Schedule)
+			}
+		})
+	}
+
+	xmlFeed, err := parser.LoadFile(filepath.Join("testdata", "checkly.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	feed, err := rss.NewFromChannel(xmlFeed.Channel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Schedule.TTL != 60 {
+		t.Errorf("expected ttl %d, got %d", 60, feed.Schedule.TTL)
+	}
+}
+
+func TestScheduleNextFetch(t *testing.T) {
+	// A Friday afternoon.
+	last := time.Date(2019, time.April, 5, 14, 20, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name     string
+		schedule rss.Schedule
+		want     time.Time
+	}{
+		{name: "no hints", schedule: rss.Schedule{}, want: last},
+		{name: "ttl", schedule: rss.Schedule{TTL: 90}, want: last.Add(90 * time.Minute)},
+		{name: "update period", schedule: rss.Schedule{UpdatePeriod: "daily", UpdateFrequency: 4}, want: last.Add(6 * time.Hour)},
+		{name: "longest wins", schedule: rss.Schedule{TTL: 30, UpdatePeriod: "hourly", UpdateFrequency: 1}, want: last.Add(time.Hour)},
+		{name: "huge ttl", schedule: rss.Schedule{TTL: math.MaxInt32}, want: last.Add(rss.MaxScheduleInterval)},
+		{name: "capped", schedule: rss.Schedule{UpdatePeriod: "yearly", UpdateFrequency: 1}, want: last.Add(rss.MaxScheduleInterval)},
+		{
+			name:     "skip hours",
+			schedule: rss.Schedule{TTL: 60, SkipHours: []int{15, 16}},
+			want:     time.Date(2019, time.April, 5, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip days",
+			schedule: rss.Schedule{TTL: 60 * 12, SkipDays: []string{"Saturday", "Sunday"}},
+			want:     time.Date(2019, time.April, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skip every day",
+			schedule: rss.Schedule{TTL: 60, SkipDays: []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}},
+			want:     last.Add(time.Hour),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.schedule.NextFetch(last); !got.Equal(tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEarliestNextFetch(t *testing.T) {
+	last := time.Date(2019, time.April, 5, 14, 20, 0, 0, time.UTC)
+	feed := &rss.Feed{Schedule: rss.Schedule{TTL: 60}}
+
+	if got, want := feed.EarliestNextFetch(last), last.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	feed.NextFetch = last.Add(2 * time.Hour)
+	if got, want := feed.EarliestNextFetch(last), feed.NextFetch; !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,10 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS guid TEXT NOT NULL DEFAULT '';
+
+-- Links are not unique across feeds, nor even within a feed for aggregators
+-- and podcasts that point every item at the same landing page.
+ALTER TABLE items DROP CONSTRAINT IF EXISTS items_link_key;
+ALTER TABLE items DROP CONSTRAINT IF EXISTS uniq_feed_link_pub;
+
+-- Items are identified by their GUID within a feed, falling back to the link
+-- for feeds that do not publish one.
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_feed_item_identity ON items (feed_id, (COALESCE(NULLIF(guid, ''), link)));
@@ -0,0 +1,2 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS summary TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS content TEXT NOT NULL DEFAULT '';
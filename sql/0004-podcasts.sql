@@ -0,0 +1,15 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS duration        INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS episode         INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS episode_image   TEXT    NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS transcript_url  TEXT    NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS transcript_type TEXT    NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS chapters_url    TEXT    NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS enclosures (
+    id      SERIAL  PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    url     TEXT    NOT NULL,
+    type    TEXT    NOT NULL DEFAULT '',
+    length  BIGINT  NOT NULL DEFAULT 0,
+    CONSTRAINT uniq_item_enclosure UNIQUE (item_id, url)
+);
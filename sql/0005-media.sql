@@ -0,0 +1,2 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS media      JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS lead_image TEXT  NOT NULL DEFAULT '';
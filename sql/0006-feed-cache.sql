@@ -0,0 +1,4 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS url           TEXT        NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS etag          TEXT        NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_modified TEXT        NOT NULL DEFAULT '';
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS next_fetch    TIMESTAMPTZ NOT NULL DEFAULT 'epoch';
@@ -0,0 +1,8 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS moved_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS feed_aliases (
+    url     TEXT    PRIMARY KEY,
+    feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS feed_aliases_feed_id ON feed_aliases (feed_id);
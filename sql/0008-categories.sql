@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id     SERIAL PRIMARY KEY,
+    name   TEXT   NOT NULL,
+    domain TEXT   NOT NULL DEFAULT ''
+);
+
+-- Categories are matched without regard to case, so that "Go" and "go" from
+-- different feeds end up as the same category.
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_category ON categories (LOWER(name), domain);
+
+CREATE TABLE IF NOT EXISTS item_categories (
+    item_id     INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (item_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS item_categories_category_id ON item_categories (category_id);
+
+CREATE TABLE IF NOT EXISTS feed_categories (
+    feed_id     INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_id, category_id)
+);
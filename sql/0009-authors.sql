@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id     SERIAL PRIMARY KEY,
+    name   TEXT   NOT NULL DEFAULT '',
+    email  TEXT   NOT NULL DEFAULT '',
+    url    TEXT   NOT NULL DEFAULT '',
+    avatar TEXT   NOT NULL DEFAULT ''
+);
+
+-- Authors are identified by their name and email address, neither of which
+-- is case sensitive.
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_author ON authors (LOWER(name), LOWER(email));
+
+-- Position keeps authors in the order they were credited in.
+CREATE TABLE IF NOT EXISTS item_authors (
+    item_id   INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (item_id, author_id)
+);
+
+CREATE INDEX IF NOT EXISTS item_authors_author_id ON item_authors (author_id);
+
+CREATE TABLE IF NOT EXISTS feed_authors (
+    feed_id   INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (feed_id, author_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS schedule JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+    feed_id       INTEGER     PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    hub           TEXT        NOT NULL,
+    topic         TEXT        NOT NULL,
+    secret        TEXT        NOT NULL,
+    state         TEXT        NOT NULL,
+    lease_expires TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS subscriptions_lease_expires ON subscriptions (lease_expires);
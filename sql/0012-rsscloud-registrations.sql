@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS cloud_registrations (
+    feed_id       INTEGER     PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    endpoint      TEXT        NOT NULL,
+    topic         TEXT        NOT NULL,
+    registered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS cloud_registrations_registered_at ON cloud_registrations (registered_at);
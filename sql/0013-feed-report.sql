@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS report JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS rejected_self_link TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE items ALTER COLUMN duration TYPE BIGINT;
@@ -0,0 +1,5 @@
+-- Feeds are identified by the address they are fetched from, not by the
+-- website they link to: a site may publish several feeds with the same link.
+ALTER TABLE feeds DROP CONSTRAINT IF EXISTS feeds_link_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS feeds_url_key ON feeds (url) WHERE url <> '';
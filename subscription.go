@@ -0,0 +1,34 @@
+package rss
+
+import (
+	"time"
+)
+
+// The states a WebSub subscription moves through. A subscription is pending
+// from the moment we ask a hub for it until the hub verifies our intent, and
+// unsubscribing from the moment we ask a hub to cancel it until the hub
+// verifies that. A hub may refuse a subscription at any time, which leaves it
+// denied.
+const (
+	SubscriptionPending       = "pending"
+	SubscriptionActive        = "active"
+	SubscriptionDenied        = "denied"
+	SubscriptionUnsubscribing = "unsubscribing"
+)
+
+// Subscription is a WebSub subscription to updates of a feed, published as
+// Topic through Hub. A feed has at most one subscription. Secret is shared
+// with the hub so that content it delivers can be authenticated, and is never
+// sent to clients. RequestedAt is when we last asked the hub to subscribe, and
+// Attempts counts the requests made since the subscription was last active, so
+// that subscriptions the hub never verified, or refused, can be tried again.
+type Subscription struct {
+	FeedID       int64      `db:"feed_id" json:"feedId"`
+	Hub          string     `db:"hub" json:"hub"`
+	Topic        string     `db:"topic" json:"topic"`
+	Secret       string     `db:"secret" json:"-"`
+	State        string     `db:"state" json:"state"`
+	LeaseExpires *time.Time `db:"lease_expires" json:"leaseExpires,omitempty"`
+	RequestedAt  *time.Time `db:"requested_at" json:"requestedAt,omitempty"`
+	Attempts     int        `db:"attempts" json:"attempts"`
+}
@@ -1,18 +1,30 @@
 package transport
 
 import (
+	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 
 	"github.com/haleyrc/rss"
 	"github.com/haleyrc/rss/parser"
+	"github.com/haleyrc/rss/rsscloud"
+	"github.com/haleyrc/rss/websub"
 )
 
-func NewServer(repo rss.Repository) http.Handler {
-	controller := NewController(repo)
+// NewServer returns the HTTP API. Feeds are downloaded with fetcher, or with a
+// fetcher using the default configuration if it is nil. Feeds that advertise a
+// WebSub hub are subscribed to through subscriber, whose callbacks are served
+// under /websub, and feeds that name an rssCloud are registered with it
+// through cloud, whose callbacks are served under /rsscloud. Either is
+// disabled if it is nil.
+func NewServer(repo rss.Repository, fetcher *parser.Fetcher, subscriber *websub.Subscriber, cloud *rsscloud.Subscriber) http.Handler {
+	controller := NewController(repo, fetcher, subscriber, cloud)
 
 	createFeedEndpoint := NewEndpoint(
 		controller.CreateFeed,
@@ -26,9 +38,57 @@ func NewServer(repo rss.Repository) http.Handler {
 		encodeResponse,
 	)
 
+	discoverFeedsEndpoint := NewEndpoint(
+		controller.DiscoverFeeds,
+		decodeDiscoverFeedsRequest,
+		encodeResponse,
+	)
+
+	refreshFeedEndpoint := NewEndpoint(
+		controller.RefreshFeed,
+		decodeRefreshFeedRequest,
+		encodeResponse,
+	)
+
+	listItemsEndpoint := NewEndpoint(
+		controller.ListItems,
+		decodeListItemsRequest,
+		encodeResponse,
+	)
+
+	getItemEndpoint := NewEndpoint(
+		controller.GetItem,
+		decodeGetItemRequest,
+		encodeResponse,
+	)
+
+	getFeedReportEndpoint := NewEndpoint(
+		controller.GetFeedReport,
+		decodeGetFeedReportRequest,
+		encodeResponse,
+	)
+
+	pingFeedEndpoint := NewEndpoint(
+		controller.PingFeed,
+		decodePingFeedRequest,
+		encodeResponse,
+	)
+
 	r := mux.NewRouter()
 	r.Handle("/feeds", createFeedEndpoint).Methods(http.MethodPost)
+	r.Handle("/feeds/discover", discoverFeedsEndpoint).Methods(http.MethodGet)
 	r.Handle("/feeds/{id}", removeFeedEndpoint).Methods(http.MethodDelete)
+	r.Handle("/feeds/{id}/refresh", refreshFeedEndpoint).Methods(http.MethodPost)
+	r.Handle("/feeds/{id}/report", getFeedReportEndpoint).Methods(http.MethodGet)
+	r.Handle("/items", listItemsEndpoint).Methods(http.MethodGet)
+	r.Handle("/items/{id}", getItemEndpoint).Methods(http.MethodGet)
+	if subscriber != nil {
+		r.Handle("/websub/{id}", subscriber).Methods(http.MethodGet, http.MethodPost)
+	}
+	if cloud != nil {
+		r.Handle("/rsscloud/{id}", cloud).Methods(http.MethodGet)
+		r.Handle("/rsscloud/{id}", pingFeedEndpoint).Methods(http.MethodPost)
+	}
 
 	return r
 }
@@ -59,7 +119,9 @@ func decodeCreateFeedRequest(r *http.Request) (interface{}, error) {
 	return request, nil
 }
 
-type Handler func(req interface{}) (interface{}, error)
+// Handler handles a decoded request. The context is cancelled when the client
+// goes away, and should be passed on to anything that may block.
+type Handler func(ctx context.Context, req interface{}) (interface{}, error)
 type DecoderFunc func(r *http.Request) (interface{}, error)
 type EncoderFunc func(w http.ResponseWriter, data interface{}, err error)
 
@@ -83,16 +145,27 @@ func (e Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		e.enc(w, nil, err)
 		return
 	}
-	data, err := e.h(req)
+	data, err := e.h(r.Context(), req)
 	e.enc(w, data, err)
 }
 
-func NewController(repo rss.Repository) Controller {
-	return Controller{repo}
+func NewController(repo rss.Repository, fetcher *parser.Fetcher, subscriber *websub.Subscriber, cloud *rsscloud.Subscriber) Controller {
+	if fetcher == nil {
+		fetcher = parser.NewFetcher(parser.FetcherConfig{})
+	}
+	return Controller{
+		repository: repo,
+		fetcher:    fetcher,
+		subscriber: subscriber,
+		cloud:      cloud,
+	}
 }
 
 type Controller struct {
 	repository rss.Repository
+	fetcher    *parser.Fetcher
+	subscriber *websub.Subscriber
+	cloud      *rsscloud.Subscriber
 }
 
 type createFeedRequest struct {
@@ -103,25 +176,349 @@ type CreateFeedResponse struct {
 	Feed *rss.Feed `json:"feed"`
 }
 
-func (h *Controller) CreateFeed(request interface{}) (interface{}, error) {
+func (h *Controller) CreateFeed(ctx context.Context, request interface{}) (interface{}, error) {
 	req := request.(createFeedRequest)
-	xmlFeed, err := parser.LoadURL(req.URL)
+	existing, err := h.repository.FindFeedByURL(req.URL)
+	if err == nil {
+		return CreateFeedResponse{Feed: existing}, nil
+	}
+	if err != rss.ErrFeedNotFound {
+		return CreateFeedResponse{}, err
+	}
+
+	url := req.URL
+	result, err := h.fetcher.Fetch(ctx, url, parser.Validators{})
+	if page, ok := err.(*parser.HTMLPageError); ok {
+		// People often paste the address of a site rather than of its
+		// feed, so subscribe to the first feed the site advertises.
+		url, err = h.discoverFeed(ctx, page)
+		if err != nil {
+			return CreateFeedResponse{}, err
+		}
+		result, err = h.fetcher.Fetch(ctx, url, parser.Validators{})
+	}
 	if err != nil {
 		return CreateFeedResponse{}, err
 	}
 
-	feed, err := rss.NewFromChannel(xmlFeed.Channel)
+	// The feed may have been found through a redirect or a web page, in
+	// which case we may already know it by its real address.
+	if result.URL != req.URL {
+		existing, err := h.repository.FindFeedByURL(result.URL)
+		if err == nil {
+			return CreateFeedResponse{Feed: existing}, nil
+		}
+		if err != rss.ErrFeedNotFound {
+			return CreateFeedResponse{}, err
+		}
+	}
+
+	feed, err := rss.NewFromFeed(result.Feed)
 	if err != nil {
 		return CreateFeedResponse{}, err
 	}
+	feed.URL = url
+	setCache(feed, result)
+	feed.NextFetch = feed.EarliestNextFetch(time.Now())
 
 	if err := h.repository.CreateFeed(feed, feed.Items...); err != nil {
 		return CreateFeedResponse{}, err
 	}
+	// If the feed has moved permanently, keep the address we were given as
+	// an alias so that subscribing to it again finds this feed.
+	if result.URL != url {
+		if err := h.repository.MoveFeed(feed, result.URL); err != nil {
+			return CreateFeedResponse{}, err
+		}
+	}
+	h.subscribe(ctx, feed, result.Feed.Channel)
 
 	return CreateFeedResponse{Feed: feed}, nil
 }
 
+// subscribe subscribes to pushed updates of feed if its channel advertises a
+// WebSub hub or an rssCloud. The feed is still fetched on schedule without a
+// subscription, so failures are only logged.
+func (c *Controller) subscribe(ctx context.Context, feed *rss.Feed, channel parser.Channel) {
+	if c.subscriber != nil {
+		if err := c.subscriber.SubscribeChannel(ctx, feed.ID, channel, feed.URL); err != nil {
+			log.Printf("error subscribing to %s: %v\n", feed.URL, err)
+		}
+	}
+	if c.cloud != nil {
+		if err := c.cloud.RegisterChannel(ctx, feed.ID, channel, feed.URL); err != nil {
+			log.Printf("error registering with cloud for %s: %v\n", feed.URL, err)
+		}
+	}
+}
+
+// discoverFeed returns the URL of the first feed advertised by a web page
+// that was fetched in place of a feed.
+func (h *Controller) discoverFeed(ctx context.Context, page *parser.HTMLPageError) (string, error) {
+	candidates, err := h.fetcher.DiscoverPage(ctx, page)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", errors.Errorf("no feeds found at %s", page.URL)
+	}
+	return candidates[0].URL, nil
+}
+
+// setCache records the HTTP validators and next fetch time from a fetch on
+// the feed.
+func setCache(feed *rss.Feed, result parser.FetchResult) {
+	now := time.Now()
+	feed.ETag = result.Validators.ETag
+	feed.LastModified = result.Validators.LastModified
+	feed.FetchedAt = &now
+	feed.NextFetch = result.NextFetch
+}
+
+type discoverFeedsRequest struct {
+	URL string `json:"url"`
+}
+
+type DiscoverFeedsResponse struct {
+	Candidates []parser.Candidate `json:"candidates"`
+}
+
+func decodeDiscoverFeedsRequest(r *http.Request) (interface{}, error) {
+	var request discoverFeedsRequest
+	request.URL = r.URL.Query().Get("url")
+	if request.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	return request, nil
+}
+
+// DiscoverFeeds lists the feeds advertised by a web page, so that clients can
+// let the user choose between them before subscribing.
+func (c *Controller) DiscoverFeeds(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(discoverFeedsRequest)
+
+	candidates, err := c.fetcher.Discover(ctx, req.URL)
+	if err != nil {
+		return DiscoverFeedsResponse{}, err
+	}
+
+	return DiscoverFeedsResponse{Candidates: candidates}, nil
+}
+
+type refreshFeedRequest struct {
+	ID int64 `json:"id"`
+}
+
+const (
+	RefreshStatusFresh       = "fresh"
+	RefreshStatusNotModified = "not modified"
+	RefreshStatusUpdated     = "updated"
+)
+
+type RefreshFeedResponse struct {
+	Status  string `json:"status"`
+	Items   int    `json:"items"`
+	MovedTo string `json:"movedTo,omitempty"`
+}
+
+func decodeRefreshFeedRequest(r *http.Request) (interface{}, error) {
+	var request refreshFeedRequest
+	id := mux.Vars(r)["id"]
+	iid, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	request.ID = iid
+	return request, nil
+}
+
+// RefreshFeed fetches a feed again and stores any new or updated items. The
+// fetch is skipped entirely if the publisher asked us to wait, and a 304 from
+// the publisher only updates the stored validators.
+func (c *Controller) RefreshFeed(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(refreshFeedRequest)
+
+	feed, err := c.repository.GetFeed(req.ID)
+	if err != nil {
+		return RefreshFeedResponse{}, err
+	}
+	if time.Now().Before(feed.NextFetch) {
+		return RefreshFeedResponse{Status: RefreshStatusFresh}, nil
+	}
+	return c.refresh(ctx, feed)
+}
+
+// refresh fetches feed and stores any new or updated items, whether or not it
+// is due.
+func (c *Controller) refresh(ctx context.Context, feed *rss.Feed) (RefreshFeedResponse, error) {
+	result, err := c.fetcher.Fetch(ctx, feed.URL, parser.Validators{
+		ETag:         feed.ETag,
+		LastModified: feed.LastModified,
+	})
+	if err != nil {
+		if !result.NextFetch.IsZero() {
+			feed.NextFetch = result.NextFetch
+			if err := c.repository.UpdateFeedCache(feed); err != nil {
+				return RefreshFeedResponse{}, err
+			}
+		}
+		return RefreshFeedResponse{}, err
+	}
+
+	setCache(feed, result)
+	movedTo, err := c.followMove(ctx, feed, result)
+	if err != nil {
+		return RefreshFeedResponse{}, err
+	}
+	if result.NotModified {
+		feed.NextFetch = feed.EarliestNextFetch(time.Now())
+		if err := c.repository.UpdateFeedCache(feed); err != nil {
+			return RefreshFeedResponse{}, err
+		}
+		return RefreshFeedResponse{Status: RefreshStatusNotModified, MovedTo: movedTo}, nil
+	}
+
+	updated, err := rss.NewFromFeed(result.Feed)
+	if err != nil {
+		return RefreshFeedResponse{}, err
+	}
+	feed.Schedule = updated.Schedule
+	feed.Report = updated.Report
+	feed.NextFetch = feed.EarliestNextFetch(time.Now())
+	for _, item := range updated.Items {
+		item.FeedID = feed.ID
+		if err := c.repository.CreateItem(item); err != nil {
+			return RefreshFeedResponse{}, err
+		}
+	}
+	if err := c.repository.UpdateFeedCache(feed); err != nil {
+		return RefreshFeedResponse{}, err
+	}
+	// Publishers may add or change hubs at any time, and a subscription
+	// that failed earlier is worth trying again.
+	c.subscribe(ctx, feed, result.Feed.Channel)
+
+	return RefreshFeedResponse{Status: RefreshStatusUpdated, Items: len(updated.Items), MovedTo: movedTo}, nil
+}
+
+type pingFeedRequest struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+}
+
+// decodePingFeedRequest reads an rssCloud notification, which is a form
+// holding the address of the feed that changed.
+func decodePingFeedRequest(r *http.Request) (interface{}, error) {
+	var request pingFeedRequest
+	id := mux.Vars(r)["id"]
+	iid, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	request.ID = iid
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	request.URL = r.PostForm.Get("url")
+	if request.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	return request, nil
+}
+
+// minPingInterval is how long after fetching a feed we ignore pings about it.
+// Anyone can send a ping, and some clouds send one for every small change, so
+// pings are not allowed to make us fetch a feed more often than this.
+const minPingInterval = time.Minute
+
+// PingFeed refreshes a feed straight away when its rssCloud tells us that it
+// has changed, regardless of when it was next due, unless it was fetched less
+// than a minute ago.
+func (c *Controller) PingFeed(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(pingFeedRequest)
+
+	if err := c.cloud.CheckPing(req.ID, req.URL); err != nil {
+		return RefreshFeedResponse{}, err
+	}
+	feed, err := c.repository.GetFeed(req.ID)
+	if err != nil {
+		return RefreshFeedResponse{}, err
+	}
+	if feed.FetchedAt != nil && time.Since(*feed.FetchedAt) < minPingInterval {
+		return RefreshFeedResponse{Status: RefreshStatusFresh}, nil
+	}
+	return c.refresh(ctx, feed)
+}
+
+// followMove updates the address feed is fetched from if the publisher has
+// moved it, either with a permanent redirect or by pointing the feed's self
+// link somewhere else. It returns the new address, or an empty string if the
+// feed has not moved.
+func (c *Controller) followMove(ctx context.Context, feed *rss.Feed, result parser.FetchResult) (string, error) {
+	url := result.URL
+	if url == "" || url == feed.URL {
+		url = c.verifiedSelfLink(ctx, feed, result)
+	}
+	if url == "" || url == feed.URL {
+		return "", nil
+	}
+	if err := c.repository.MoveFeed(feed, url); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// verifiedSelfLink returns the self link of a freshly fetched feed if it
+// points somewhere other than where the feed was fetched from, and the same
+// feed really is published there. Self links are often stale or copied from a
+// template, so we don't follow them blindly. A self link that fails the check
+// is remembered in feed.RejectedSelfLink and not checked again until it
+// changes.
+func (c *Controller) verifiedSelfLink(ctx context.Context, feed *rss.Feed, result parser.FetchResult) string {
+	self := result.Feed.Channel.SelfLink()
+	if self == "" || self == feed.URL || self == feed.RejectedSelfLink {
+		return ""
+	}
+	moved, err := c.fetcher.Fetch(ctx, self, parser.Validators{})
+	if err != nil || moved.Feed.Channel.SelfLink() != self {
+		feed.RejectedSelfLink = self
+		return ""
+	}
+	return moved.URL
+}
+
+type getFeedReportRequest struct {
+	ID int64 `json:"id"`
+}
+
+type GetFeedReportResponse struct {
+	Report rss.Report `json:"report"`
+}
+
+func decodeGetFeedReportRequest(r *http.Request) (interface{}, error) {
+	var request getFeedReportRequest
+	id := mux.Vars(r)["id"]
+	iid, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	request.ID = iid
+	return request, nil
+}
+
+// GetFeedReport returns the validation report from the last time a feed was
+// fetched, explaining any items that were left out.
+func (c *Controller) GetFeedReport(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(getFeedReportRequest)
+
+	feed, err := c.repository.GetFeed(req.ID)
+	if err != nil {
+		return GetFeedReportResponse{}, err
+	}
+
+	return GetFeedReportResponse{Report: feed.Report}, nil
+}
+
 type removeFeedRequest struct {
 	ID int64 `json:"id"`
 }
@@ -141,12 +538,99 @@ func decodeRemoveFeedRequest(r *http.Request) (interface{}, error) {
 	return request, nil
 }
 
-func (c *Controller) RemoveFeed(request interface{}) (interface{}, error) {
+func (c *Controller) RemoveFeed(ctx context.Context, request interface{}) (interface{}, error) {
 	req := request.(removeFeedRequest)
 
+	if c.subscriber != nil {
+		if err := c.subscriber.Unsubscribe(ctx, req.ID); err != nil {
+			log.Printf("error unsubscribing from feed %d: %v\n", req.ID, err)
+		}
+	}
+	if c.cloud != nil {
+		if err := c.cloud.Unregister(req.ID); err != nil {
+			log.Printf("error unregistering feed %d from cloud: %v\n", req.ID, err)
+		}
+	}
 	if err := c.repository.RemoveFeed(req.ID); err != nil {
 		return removeFeedResponse{}, err
 	}
 
 	return removeFeedResponse{Status: "success"}, nil
 }
+
+type listItemsRequest struct {
+	Limit    int    `json:"limit"`
+	Category string `json:"category"`
+	Author   string `json:"author"`
+}
+
+type ListItemsResponse struct {
+	Items []*rss.Item `json:"items"`
+}
+
+func decodeListItemsRequest(r *http.Request) (interface{}, error) {
+	var request listItemsRequest
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, err
+		}
+		request.Limit = l
+	}
+	request.Category = r.URL.Query().Get("category")
+	request.Author = r.URL.Query().Get("author")
+	if request.Category != "" && request.Author != "" {
+		return nil, errors.New("items can be filtered by category or by author, but not both")
+	}
+	return request, nil
+}
+
+func (c *Controller) ListItems(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(listItemsRequest)
+
+	var items []*rss.Item
+	var err error
+	switch {
+	case req.Category != "":
+		items, err = c.repository.ListItemsByCategory(req.Category, req.Limit)
+	case req.Author != "":
+		items, err = c.repository.ListItemsByAuthor(req.Author, req.Limit)
+	default:
+		items, err = c.repository.ListItems(req.Limit)
+	}
+	if err != nil {
+		return ListItemsResponse{}, err
+	}
+
+	return ListItemsResponse{Items: items}, nil
+}
+
+type getItemRequest struct {
+	ID int64 `json:"id"`
+}
+
+type GetItemResponse struct {
+	Item *rss.Item `json:"item"`
+}
+
+func decodeGetItemRequest(r *http.Request) (interface{}, error) {
+	var request getItemRequest
+	id := mux.Vars(r)["id"]
+	iid, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	request.ID = iid
+	return request, nil
+}
+
+func (c *Controller) GetItem(ctx context.Context, request interface{}) (interface{}, error) {
+	req := request.(getItemRequest)
+
+	item, err := c.repository.GetItem(req.ID)
+	if err != nil {
+		return GetItemResponse{}, err
+	}
+
+	return GetItemResponse{Item: item}, nil
+}
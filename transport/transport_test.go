@@ -3,17 +3,24 @@ package transport_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/haleyrc/rss"
 	"github.com/haleyrc/rss/mock"
+	"github.com/haleyrc/rss/rsscloud"
 	"github.com/haleyrc/rss/transport"
 )
 
@@ -29,7 +36,7 @@ func TestMain(m *testing.M) {
 }
 
 func TestCreateFeed(t *testing.T) {
-	srv := transport.NewServer(repo)
+	srv := transport.NewServer(repo, nil, nil, nil)
 	server := httptest.NewServer(srv)
 	defer server.Close()
 
@@ -57,3 +64,703 @@ func TestCreateFeed(t *testing.T) {
 	b, _ := httputil.DumpResponse(deleteResponse, true)
 	fmt.Println(string(b))
 }
+
+func TestGetItem(t *testing.T) {
+	srv := transport.NewServer(repo, nil, nil, nil)
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	item, err := rss.NewItem(1, "Episode 1", "https://podcast.example.com/", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	item.Enclosures = []*rss.Enclosure{{URL: "https://cdn.podcast.example.com/episode-1.mp3", Type: "audio/mpeg", Length: 1024}}
+	item.Duration = 1845
+	if err := repo.CreateItem(item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	getResponse, err := http.Get(fmt.Sprintf("%s/items/%d", server.URL, item.ID))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer getResponse.Body.Close()
+
+	var resp struct {
+		Data transport.GetItemResponse `json:"data"`
+	}
+	if err := json.NewDecoder(getResponse.Body).Decode(&resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := resp.Data.Item
+	if got == nil {
+		t.Fatalf("expected item, got none")
+	}
+	if got.Duration != item.Duration {
+		t.Errorf("expected duration %d, got %d", item.Duration, got.Duration)
+	}
+	if len(got.Enclosures) != 1 {
+		t.Fatalf("expected 1 enclosure, got %d", len(got.Enclosures))
+	}
+	if got.Enclosures[0].URL != item.Enclosures[0].URL {
+		t.Errorf("expected enclosure url %q, got %q", item.Enclosures[0].URL, got.Enclosures[0].URL)
+	}
+}
+
+func TestListItemsByCategory(t *testing.T) {
+	srv := transport.NewServer(repo, nil, nil, nil)
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	for i, categories := range [][]string{{"Gardening"}, {"gardening", "Tools"}, {"Cooking"}} {
+		item, err := rss.NewItem(1, fmt.Sprintf("Post %d", i), fmt.Sprintf("https://garden.example.com/%d", i), time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, name := range categories {
+			item.Categories = append(item.Categories, &rss.Category{Name: name})
+		}
+		if err := repo.CreateItem(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	listResponse, err := http.Get(server.URL + "/items?category=Gardening")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listResponse.Body.Close()
+
+	var resp struct {
+		Data transport.ListItemsResponse `json:"data"`
+	}
+	if err := json.NewDecoder(listResponse.Body).Decode(&resp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Data.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(resp.Data.Items))
+	}
+	for _, item := range resp.Data.Items {
+		if !strings.HasPrefix(item.Link, "https://garden.example.com/") || item.Link == "https://garden.example.com/2" {
+			t.Errorf("unexpected item %q", item.Link)
+		}
+	}
+}
+
+func TestListItemsByAuthor(t *testing.T) {
+	srv := transport.NewServer(repo, nil, nil, nil)
+	server := httptest.NewServer(srv)
+	defer server.Close()
+
+	for i, author := range []rss.Author{{Name: "Ada"}, {Name: "Grace", Email: "grace@example.com"}} {
+		item, err := rss.NewItem(1, fmt.Sprintf("Post %d", i), fmt.Sprintf("https://byline.example.com/%d", i), time.Now())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		author := author
+		item.Authors = []*rss.Author{&author}
+		if err := repo.CreateItem(item); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	testcases := []struct {
+		query string
+		links []string
+		err   bool
+	}{
+		{query: "author=ada", links: []string{"https://byline.example.com/0"}},
+		{query: "author=GRACE@example.com", links: []string{"https://byline.example.com/1"}},
+		{query: "author=nobody", links: nil},
+		{query: "author=ada&category=Gardening", err: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.query, func(t *testing.T) {
+			listResponse, err := http.Get(server.URL + "/items?" + tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer listResponse.Body.Close()
+
+			var resp struct {
+				Data  transport.ListItemsResponse `json:"data"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.NewDecoder(listResponse.Body).Decode(&resp); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err {
+				if resp.Error == nil {
+					t.Fatalf("expected error, but got none")
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("unexpected error: %s", resp.Error.Message)
+			}
+			if len(resp.Data.Items) != len(tc.links) {
+				t.Fatalf("expected %d items, got %d", len(tc.links), len(resp.Data.Items))
+			}
+			for i, item := range resp.Data.Items {
+				if item.Link != tc.links[i] {
+					t.Errorf("expected link %q, got %q", tc.links[i], item.Link)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshFeed(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "hackernews.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	const etag = `"v1"`
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(body)
+	}))
+	defer feedServer.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	createResponse, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, feedServer.URL)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer createResponse.Body.Close()
+
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(createResponse.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+
+	feed, err := repo.GetFeed(created.Data.Feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.ETag != etag {
+		t.Errorf("expected etag %q, got %q", etag, feed.ETag)
+	}
+
+	refreshResponse, err := http.Post(fmt.Sprintf("%s/feeds/%d/refresh", server.URL, feed.ID), "application/json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer refreshResponse.Body.Close()
+
+	var refreshed struct {
+		Data transport.RefreshFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(refreshResponse.Body).Decode(&refreshed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refreshed.Data.Status != transport.RefreshStatusNotModified {
+		t.Errorf("expected status %q, got %q", transport.RefreshStatusNotModified, refreshed.Data.Status)
+	}
+}
+
+func TestRefreshFeedSchedule(t *testing.T) {
+	const body = `<rss version="2.0"><channel><title>Scheduled</title><description>D</description><link>https://scheduled.example.com/</link><ttl>30</ttl><item><title>Post</title><link>https://scheduled.example.com/1</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item></channel></rss>`
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer feedServer.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	createResponse, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, feedServer.URL)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer createResponse.Body.Close()
+
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(createResponse.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+	if created.Data.Feed.Schedule.TTL != 30 {
+		t.Errorf("expected ttl %d, got %d", 30, created.Data.Feed.Schedule.TTL)
+	}
+	if wait := time.Until(created.Data.Feed.NextFetch); wait < 29*time.Minute || wait > 30*time.Minute {
+		t.Errorf("expected next fetch in 30 minutes, got %v", wait)
+	}
+
+	refreshResponse, err := http.Post(fmt.Sprintf("%s/feeds/%d/refresh", server.URL, created.Data.Feed.ID), "application/json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer refreshResponse.Body.Close()
+
+	var refreshed struct {
+		Data transport.RefreshFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(refreshResponse.Body).Decode(&refreshed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refreshed.Data.Status != transport.RefreshStatusFresh {
+		t.Errorf("expected status %q, got %q", transport.RefreshStatusFresh, refreshed.Data.Status)
+	}
+}
+
+func TestCreateFeedFromPage(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "atom.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var pageRequests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pageRequests, 1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/atom+xml" title="Everything" href="/atom.xml"></head></html>`))
+	})
+	mux.HandleFunc("/atom.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	discoverResponse, err := http.Get(server.URL + "/feeds/discover?url=" + site.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer discoverResponse.Body.Close()
+
+	var discovered struct {
+		Data transport.DiscoverFeedsResponse `json:"data"`
+	}
+	if err := json.NewDecoder(discoverResponse.Body).Decode(&discovered); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(discovered.Data.Candidates) != 1 {
+		t.Fatalf("expected 1 candidate, got %d", len(discovered.Data.Candidates))
+	}
+	if want := "Everything"; discovered.Data.Candidates[0].Title != want {
+		t.Errorf("expected title %q, got %q", want, discovered.Data.Candidates[0].Title)
+	}
+
+	atomic.StoreInt32(&pageRequests, 0)
+	createResponse, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, site.URL)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer createResponse.Body.Close()
+
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(createResponse.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+	if want := site.URL + "/atom.xml"; created.Data.Feed.URL != want {
+		t.Errorf("expected url %q, got %q", want, created.Data.Feed.URL)
+	}
+	if n := atomic.LoadInt32(&pageRequests); n != 1 {
+		t.Errorf("expected the page to be requested once, got %d requests", n)
+	}
+}
+
+func TestRefreshMovedFeed(t *testing.T) {
+	const feedTemplate = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Moving Feed</title>
+	<description>A feed that keeps moving.</description>
+	<link>https://moving.example.org/</link>
+	<atom:link rel="self" type="application/rss+xml" href="%s"/>
+	<item>
+		<title>Hello</title>
+		<link>https://moving.example.org/hello</link>
+		<pubDate>Mon, 08 Apr 2019 12:00:00 GMT</pubDate>
+	</item>
+</channel>
+</rss>`
+
+	moved := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		if moved {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		fmt.Fprintf(w, feedTemplate, "/old")
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, feedTemplate, "/newer")
+	})
+	mux.HandleFunc("/newer", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, feedTemplate, "/newer")
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	subscribe := func(url string) *rss.Feed {
+		resp, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, url)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		var created struct {
+			Data transport.CreateFeedResponse `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if created.Data.Feed == nil {
+			t.Fatalf("expected feed, got none")
+		}
+		return created.Data.Feed
+	}
+	refresh := func(id int64) transport.RefreshFeedResponse {
+		resp, err := http.Post(fmt.Sprintf("%s/feeds/%d/refresh", server.URL, id), "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		var refreshed struct {
+			Data transport.RefreshFeedResponse `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&refreshed); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return refreshed.Data
+	}
+
+	feed := subscribe(site.URL + "/old")
+
+	moved = true
+	if got := refresh(feed.ID); got.MovedTo != site.URL+"/new" {
+		t.Errorf("expected feed to move to %q, got %q", site.URL+"/new", got.MovedTo)
+	}
+	if got := refresh(feed.ID); got.MovedTo != site.URL+"/newer" {
+		t.Errorf("expected feed to move to %q, got %q", site.URL+"/newer", got.MovedTo)
+	}
+	if got := refresh(feed.ID); got.MovedTo != "" {
+		t.Errorf("expected feed to stay put, got moved to %q", got.MovedTo)
+	}
+
+	stored, err := repo.GetFeed(feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := site.URL + "/newer"; stored.URL != want {
+		t.Errorf("expected url %q, got %q", want, stored.URL)
+	}
+	if stored.MovedAt == nil {
+		t.Errorf("expected moved at to be set")
+	}
+	wantAliases := []string{site.URL + "/new", site.URL + "/old"}
+	if strings.Join(stored.Aliases, " ") != strings.Join(wantAliases, " ") {
+		t.Errorf("expected aliases %q, got %q", wantAliases, stored.Aliases)
+	}
+
+	if again := subscribe(site.URL + "/old"); again.ID != feed.ID {
+		t.Errorf("expected resubscribing to find feed %d, got %d", feed.ID, again.ID)
+	}
+}
+
+func TestCreateRedirectedFeed(t *testing.T) {
+	body, err := ioutil.ReadFile(filepath.Join("..", "testdata", "atom.xml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/redirected.xml", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/redirected.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, site.URL+"/old.xml")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+
+	found, err := repo.FindFeedByURL(site.URL + "/old.xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found.ID != created.Data.Feed.ID {
+		t.Errorf("expected the requested url to find feed %d, got %d", created.Data.Feed.ID, found.ID)
+	}
+	if want := site.URL + "/redirected.xml"; found.URL != want {
+		t.Errorf("expected url %q, got %q", want, found.URL)
+	}
+}
+
+func TestRefreshRejectedSelfLink(t *testing.T) {
+	const feedTemplate = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Templated Feed</title>
+	<description>A feed with a stale self link.</description>
+	<link>https://templated.example.org/</link>
+	<atom:link rel="self" type="application/rss+xml" href="%s"/>
+	<item>
+		<title>Hello</title>
+		<link>https://templated.example.org/hello</link>
+		<pubDate>Mon, 08 Apr 2019 12:00:00 GMT</pubDate>
+	</item>
+</channel>
+</rss>`
+
+	var selfRequests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, feedTemplate, "/template.xml")
+	})
+	mux.HandleFunc("/template.xml", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&selfRequests, 1)
+		http.NotFound(w, r)
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, site.URL+"/feed.xml")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Post(fmt.Sprintf("%s/feeds/%d/refresh", server.URL, created.Data.Feed.ID), "application/json", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if n := atomic.LoadInt32(&selfRequests); n != 1 {
+		t.Errorf("expected the self link to be checked once, got %d requests", n)
+	}
+	stored, err := repo.GetFeed(created.Data.Feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := site.URL + "/feed.xml"; stored.URL != want {
+		t.Errorf("expected url %q, got %q", want, stored.URL)
+	}
+	if want := site.URL + "/template.xml"; stored.RejectedSelfLink != want {
+		t.Errorf("expected rejected self link %q, got %q", want, stored.RejectedSelfLink)
+	}
+}
+
+func TestPingFeed(t *testing.T) {
+	cloudServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `<?xml version="1.0"?><notifyResult success="true" msg="Thanks for the registration."/>`)
+	}))
+	defer cloudServer.Close()
+	cloudURL, err := url.Parse(cloudServer.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items := `<item><title>First</title><link>https://pinged.example.com/1</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item>`
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss version="2.0"><channel><title>Pinged</title><description>D</description><link>https://pinged.example.com/</link><ttl>60</ttl><cloud domain=%q port=%q path="/pleaseNotify" registerProcedure="" protocol="http-post"/>%s</channel></rss>`, cloudURL.Hostname(), cloudURL.Port(), items)
+	}))
+	defer feedServer.Close()
+
+	var handler http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	cloud, err := rsscloud.NewSubscriber(repo, rsscloud.Config{CallbackURL: server.URL + "/rsscloud"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler = transport.NewServer(repo, nil, nil, cloud)
+
+	createResponse, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, feedServer.URL)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer createResponse.Body.Close()
+
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(createResponse.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+	id := created.Data.Feed.ID
+
+	// The feed is not due for an hour, but a ping refreshes it anyway
+	// unless it was fetched less than a minute ago.
+	items += `<item><title>Second</title><link>https://pinged.example.com/2</link><pubDate>Tue, 02 Apr 2019 10:00:00 GMT</pubDate></item>`
+
+	testcases := []struct {
+		name       string
+		url        string
+		fetchedAgo time.Duration
+		status     string
+		items      int
+		error      bool
+	}{
+		{"unknown feed", "https://elsewhere.example.com/feed", 0, "", 0, true},
+		{"just fetched", feedServer.URL, 0, transport.RefreshStatusFresh, 0, false},
+		{"registered feed", feedServer.URL, 2 * time.Minute, transport.RefreshStatusUpdated, 2, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed, err := repo.GetFeed(id)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			fetchedAt := time.Now().Add(-tc.fetchedAgo)
+			feed.FetchedAt = &fetchedAt
+			if err := repo.UpdateFeedCache(feed); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			pingResponse, err := http.PostForm(fmt.Sprintf("%s/rsscloud/%d", server.URL, id), url.Values{"url": {tc.url}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer pingResponse.Body.Close()
+
+			var pinged struct {
+				Data  transport.RefreshFeedResponse `json:"data"`
+				Error *transport.Error              `json:"error"`
+			}
+			if err := json.NewDecoder(pingResponse.Body).Decode(&pinged); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.error {
+				if pinged.Error == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if pinged.Error != nil {
+				t.Fatalf("unexpected error: %s", pinged.Error.Message)
+			}
+			if pinged.Data.Status != tc.status {
+				t.Errorf("expected status %q, got %q", tc.status, pinged.Data.Status)
+			}
+			if pinged.Data.Items != tc.items {
+				t.Errorf("expected %d items, got %d", tc.items, pinged.Data.Items)
+			}
+		})
+	}
+}
+
+func TestGetFeedReport(t *testing.T) {
+	const body = `<rss version="2.0"><channel><title>Reported</title><description>D</description><link>https://reported.example.com/</link>` +
+		`<item><title>Good</title><link>https://reported.example.com/1</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item>` +
+		`<item><title>Bad</title><link>https://reported.example.com/2</link><pubDate>sometime</pubDate></item></channel></rss>`
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body)
+	}))
+	defer feedServer.Close()
+
+	server := httptest.NewServer(transport.NewServer(repo, nil, nil, nil))
+	defer server.Close()
+
+	createResponse, err := http.Post(server.URL+"/feeds", "application/json", strings.NewReader(fmt.Sprintf(`{"url":%q}`, feedServer.URL)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer createResponse.Body.Close()
+
+	var created struct {
+		Data transport.CreateFeedResponse `json:"data"`
+	}
+	if err := json.NewDecoder(createResponse.Body).Decode(&created); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Data.Feed == nil {
+		t.Fatalf("expected feed, got none")
+	}
+
+	reportResponse, err := http.Get(fmt.Sprintf("%s/feeds/%d/report", server.URL, created.Data.Feed.ID))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reportResponse.Body.Close()
+
+	var report struct {
+		Data transport.GetFeedReportResponse `json:"data"`
+	}
+	if err := json.NewDecoder(reportResponse.Body).Decode(&report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issues := report.Data.Report.Issues
+	if len(issues) != 1 {
+		t.Fatalf("expected %d issue, got %d", 1, len(issues))
+	}
+	if issues[0].Item != 2 {
+		t.Errorf("expected item %d, got %d", 2, issues[0].Item)
+	}
+	if issues[0].Severity != rss.SeverityError {
+		t.Errorf("expected severity %q, got %q", rss.SeverityError, issues[0].Severity)
+	}
+	if issues[0].Value != "sometime" {
+		t.Errorf("expected value %q, got %q", "sometime", issues[0].Value)
+	}
+}
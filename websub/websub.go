@@ -0,0 +1,475 @@
+// Package websub subscribes to feeds through WebSub hubs, so that publishers
+// can push new items to us as soon as they are published rather than waiting
+// for the next fetch.
+//
+// A Subscriber asks hubs for subscriptions and serves the callbacks that hubs
+// make: a GET to verify that we really asked to subscribe or unsubscribe, and
+// a POST carrying new content, signed with a secret shared with the hub.
+// Leases granted by hubs expire, so Run should be left running to renew them.
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+// Defaults used by NewSubscriber for any Config fields left at their zero
+// value.
+const (
+	DefaultLeaseSeconds = 10 * 24 * 60 * 60
+	DefaultRenewBefore  = 24 * time.Hour
+	DefaultTimeout      = parser.DefaultTimeout
+)
+
+// A subscription that a hub has not verified within pendingTimeout of our
+// request is asked for again; the hub may never have reached us. One the hub
+// denied is asked for again after deniedBackoff, doubling with every refusal
+// up to maxDeniedBackoff.
+const (
+	pendingTimeout   = time.Hour
+	deniedBackoff    = 24 * time.Hour
+	maxDeniedBackoff = 30 * 24 * time.Hour
+)
+
+// Config controls how a Subscriber talks to hubs.
+type Config struct {
+	// CallbackURL is the public address that the Subscriber is served at,
+	// such as https://reader.example.com/websub. Hubs are given this
+	// address followed by the ID of the feed.
+	CallbackURL string
+
+	// LeaseSeconds is how long we ask hubs to keep a subscription for.
+	// Hubs are free to grant a different lease.
+	LeaseSeconds int
+
+	// RenewBefore is how long before a lease expires that it is renewed.
+	RenewBefore time.Duration
+
+	// Timeout bounds each request to a hub.
+	Timeout time.Duration
+}
+
+// Subscriber manages WebSub subscriptions to feeds and stores the content
+// that hubs push to it. It is an http.Handler serving the callbacks for every
+// feed at paths ending in the feed's ID.
+type Subscriber struct {
+	repository   rss.Repository
+	client       *http.Client
+	callbackURL  string
+	leaseSeconds int
+	renewBefore  time.Duration
+}
+
+func NewSubscriber(repo rss.Repository, config Config) *Subscriber {
+	if config.LeaseSeconds <= 0 {
+		config.LeaseSeconds = DefaultLeaseSeconds
+	}
+	if config.RenewBefore <= 0 {
+		config.RenewBefore = DefaultRenewBefore
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &Subscriber{
+		repository:   repo,
+		client:       &http.Client{Timeout: config.Timeout},
+		callbackURL:  strings.TrimRight(config.CallbackURL, "/"),
+		leaseSeconds: config.LeaseSeconds,
+		renewBefore:  config.RenewBefore,
+	}
+}
+
+// SubscribeChannel subscribes to updates of a feed through the first hub its
+// channel advertises. The topic is the channel's self link, or url, the
+// address the feed was fetched from, if it has none. Nothing is done if the
+// channel has no hub, or the feed already has a subscription to the same topic
+// through the same hub, unless it is one the hub has left unverified for too
+// long or denied long enough ago to ask again.
+func (s *Subscriber) SubscribeChannel(ctx context.Context, feedID int64, c parser.Channel, url string) error {
+	hubs := c.HubLinks()
+	if len(hubs) == 0 {
+		return nil
+	}
+	topic := c.SelfLink()
+	if topic == "" {
+		topic = url
+	}
+
+	existing, err := s.repository.GetSubscription(feedID)
+	if err != nil && err != rss.ErrSubscriptionNotFound {
+		return err
+	}
+	if err == nil && existing.Hub == hubs[0] && existing.Topic == topic && !retryDue(existing, time.Now()) {
+		return nil
+	}
+	return s.Subscribe(ctx, feedID, hubs[0], topic)
+}
+
+// retryDue reports whether a subscription should be asked for again at now.
+func retryDue(sub *rss.Subscription, now time.Time) bool {
+	switch sub.State {
+	case rss.SubscriptionUnsubscribing:
+		return true
+	case rss.SubscriptionPending:
+		return sub.RequestedAt == nil || now.Sub(*sub.RequestedAt) >= pendingTimeout
+	case rss.SubscriptionDenied:
+		backoff := deniedBackoff
+		for i := 1; i < sub.Attempts && backoff < maxDeniedBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > maxDeniedBackoff {
+			backoff = maxDeniedBackoff
+		}
+		return sub.RequestedAt == nil || now.Sub(*sub.RequestedAt) >= backoff
+	default:
+		return false
+	}
+}
+
+// Subscribe asks hub to send us updates to topic, which are stored as items
+// of the feed with the given ID. The subscription is only active once the hub
+// has verified it. If the hub can not be reached, or does not accept the
+// request, the feed is left with the subscription it had before, if any, so
+// that it can be tried again later.
+func (s *Subscriber) Subscribe(ctx context.Context, feedID int64, hub, topic string) error {
+	previous, err := s.repository.GetSubscription(feedID)
+	if err != nil && err != rss.ErrSubscriptionNotFound {
+		return err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	sub := &rss.Subscription{
+		FeedID:      feedID,
+		Hub:         hub,
+		Topic:       topic,
+		Secret:      secret,
+		State:       rss.SubscriptionPending,
+		RequestedAt: &now,
+		Attempts:    1,
+	}
+	if previous != nil && previous.Hub == hub && previous.Topic == topic && previous.State != rss.SubscriptionActive {
+		sub.Attempts = previous.Attempts + 1
+	}
+	// The hub may verify our intent before it has replied to us, so the
+	// subscription has to be stored first.
+	if err := s.repository.SaveSubscription(sub); err != nil {
+		return err
+	}
+	if err := s.request(ctx, sub, "subscribe"); err != nil {
+		var rerr error
+		if previous != nil {
+			rerr = s.repository.SaveSubscription(previous)
+		} else {
+			rerr = s.repository.RemoveSubscription(feedID)
+		}
+		if rerr != nil {
+			log.Printf("error restoring subscription to %s: %v\n", topic, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Unsubscribe asks the hub to stop sending us updates to a feed. It does
+// nothing if the feed has no subscription.
+func (s *Subscriber) Unsubscribe(ctx context.Context, feedID int64) error {
+	sub, err := s.repository.GetSubscription(feedID)
+	if err == rss.ErrSubscriptionNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sub.State = rss.SubscriptionUnsubscribing
+	if err := s.repository.SaveSubscription(sub); err != nil {
+		return err
+	}
+	return s.request(ctx, sub, "unsubscribe")
+}
+
+// Renew asks hubs to extend every active subscription whose lease runs out
+// within RenewBefore of now. A hub that fails to renew a subscription does not
+// stop the others from being renewed; the failure is logged and the renewal
+// is tried again on the next call.
+func (s *Subscriber) Renew(ctx context.Context, now time.Time) error {
+	subs, err := s.repository.ListSubscriptionsExpiring(now.Add(s.renewBefore))
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if err := s.request(ctx, sub, "subscribe"); err != nil {
+			log.Printf("error renewing subscription to %s: %v\n", sub.Topic, err)
+		}
+	}
+	return nil
+}
+
+// Run renews expiring subscriptions every interval until ctx is cancelled.
+func (s *Subscriber) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Renew(ctx, time.Now()); err != nil {
+			log.Printf("error renewing subscriptions: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// request sends a subscription request to the hub of sub. Hubs verify
+// requests asynchronously, so a 2xx response only means that the request was
+// accepted.
+func (s *Subscriber) request(ctx context.Context, sub *rss.Subscription, mode string) error {
+	form := url.Values{
+		"hub.callback": {s.callback(sub.FeedID)},
+		"hub.mode":     {mode},
+		"hub.topic":    {sub.Topic},
+	}
+	if mode == "subscribe" {
+		form.Set("hub.lease_seconds", strconv.Itoa(s.leaseSeconds))
+		form.Set("hub.secret", sub.Secret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", parser.DefaultUserAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("hub %s responded to %s request with status %d", sub.Hub, mode, resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *Subscriber) callback(feedID int64) string {
+	return s.callbackURL + "/" + strconv.FormatInt(feedID, 10)
+}
+
+// ServeHTTP handles the callbacks made by hubs for the feed whose ID ends the
+// request path.
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	feedID, err := strconv.ParseInt(path.Base(r.URL.Path), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.verify(w, r, feedID)
+	case http.MethodPost:
+		s.distribute(w, r, feedID)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers a hub checking that we asked to subscribe or unsubscribe, or
+// telling us that it has refused a subscription. Echoing the challenge
+// confirms the request; anything else makes the hub abandon it.
+func (s *Subscriber) verify(w http.ResponseWriter, r *http.Request, feedID int64) {
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+	topic := q.Get("hub.topic")
+	challenge := q.Get("hub.challenge")
+
+	sub, err := s.repository.GetSubscription(feedID)
+	if err != nil && err != rss.ErrSubscriptionNotFound {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	found := err == nil && sub.Topic == topic
+
+	switch mode {
+	case "subscribe":
+		if !found || challenge == "" || (sub.State != rss.SubscriptionPending && sub.State != rss.SubscriptionActive) {
+			http.NotFound(w, r)
+			return
+		}
+		lease, err := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = s.leaseSeconds
+		}
+		expires := time.Now().Add(time.Duration(lease) * time.Second)
+		sub.State = rss.SubscriptionActive
+		sub.LeaseExpires = &expires
+		sub.Attempts = 0
+		if err := s.repository.SaveSubscription(sub); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+	case "unsubscribe":
+		// A feed may have been removed, along with its subscription,
+		// before the hub got round to checking. We don't want its
+		// updates either way.
+		if err == rss.ErrSubscriptionNotFound {
+			break
+		}
+		if !found || challenge == "" || sub.State != rss.SubscriptionUnsubscribing {
+			http.NotFound(w, r)
+			return
+		}
+		if err := s.repository.RemoveSubscription(feedID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+	case "denied":
+		if found {
+			log.Printf("hub %s denied subscription to %s: %s\n", sub.Hub, topic, q.Get("hub.reason"))
+			sub.State = rss.SubscriptionDenied
+			sub.LeaseExpires = nil
+			if err := s.repository.SaveSubscription(sub); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, challenge)
+}
+
+// distribute stores the items in content pushed by a hub, and the validation
+// report and publishing schedule of the feed, as a fetch would. Content whose
+// signature does not match the secret we shared with the hub is acknowledged
+// but ignored, as the specification requires, so that a forger learns
+// nothing from the response.
+func (s *Subscriber) distribute(w http.ResponseWriter, r *http.Request, feedID int64) {
+	sub, err := s.repository.GetSubscription(feedID)
+	if err == rss.ErrSubscriptionNotFound || (err == nil && sub.State != rss.SubscriptionActive) {
+		http.Error(w, "no active subscription", http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, parser.DefaultMaxBodyBytes+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > parser.DefaultMaxBodyBytes {
+		http.Error(w, parser.ErrBodyTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !validSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		log.Printf("ignoring content for %s with invalid signature\n", sub.Topic)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	doc, err := parser.LoadDocument(sub.Topic, r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	updated, err := rss.NewFromFeed(doc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	feed, err := s.repository.GetFeed(feedID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, item := range updated.Items {
+		item.FeedID = feedID
+		if err := s.repository.CreateItem(item); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	feed.Schedule = updated.Schedule
+	feed.Report = updated.Report
+	if err := s.repository.UpdateFeedCache(feed); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// signatureHashes are the hash functions that hubs may sign content with.
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// validSignature reports whether signature, the value of an X-Hub-Signature
+// header, is a valid HMAC of body keyed with secret.
+func validSignature(secret, signature string, body []byte) bool {
+	parts := strings.SplitN(signature, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	newHash, ok := signatureHashes[strings.ToLower(parts[0])]
+	if !ok {
+		return false
+	}
+	want, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// newSecret returns a random secret to share with a hub.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
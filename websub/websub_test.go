@@ -0,0 +1,416 @@
+package websub_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/mock"
+	"github.com/haleyrc/rss/parser"
+	"github.com/haleyrc/rss/websub"
+)
+
+const topic = "https://example.com/feed.xml"
+
+// hub stands in for a WebSub hub. It verifies every request it receives
+// before replying, granting leases of leaseSeconds, and keeps the requests so
+// that tests can inspect them.
+type hub struct {
+	t            *testing.T
+	leaseSeconds string
+	requests     []url.Values
+}
+
+func (h *hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.t.Errorf("unexpected error: %v", err)
+		return
+	}
+	h.requests = append(h.requests, r.PostForm)
+
+	const challenge = "a1b2c3"
+	verify := r.PostForm.Get("hub.callback") + "?" + url.Values{
+		"hub.mode":          {r.PostForm.Get("hub.mode")},
+		"hub.topic":         {r.PostForm.Get("hub.topic")},
+		"hub.challenge":     {challenge},
+		"hub.lease_seconds": {h.leaseSeconds},
+	}.Encode()
+	resp, err := http.Get(verify)
+	if err != nil {
+		h.t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != challenge {
+		h.t.Errorf("expected challenge %q to be echoed, got %d %q", challenge, resp.StatusCode, body)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// setup returns a subscriber to a feed stored in a fresh repository, with its
+// callbacks served over HTTP.
+func setup(t *testing.T) (rss.Repository, *websub.Subscriber, *rss.Feed, func()) {
+	repo := mock.NewRepository()
+	feed := &rss.Feed{Title: "Example", URL: topic}
+	if err := repo.CreateFeed(feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var subscriber *websub.Subscriber
+	callbacks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subscriber.ServeHTTP(w, r)
+	}))
+	subscriber = websub.NewSubscriber(repo, websub.Config{CallbackURL: callbacks.URL + "/websub/"})
+	return repo, subscriber, feed, callbacks.Close
+}
+
+func distribute(t *testing.T, callback, signature, body string) {
+	req, err := http.NewRequest(http.MethodPost, callback, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/rss+xml")
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature", signature)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func content(title string) string {
+	return `<rss version="2.0"><channel><title>Example</title><description>D</description><link>https://example.com/</link>` +
+		`<item><title>` + title + `</title><link>/posts/1</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item></channel></rss>`
+}
+
+func TestSubscribe(t *testing.T) {
+	repo, subscriber, feed, done := setup(t)
+	defer done()
+
+	h := &hub{t: t, leaseSeconds: "3600"}
+	hubServer := httptest.NewServer(h)
+	defer hubServer.Close()
+
+	if err := subscriber.Subscribe(context.Background(), feed.ID, hubServer.URL, topic); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.requests) != 1 {
+		t.Fatalf("expected %d hub request, got %d", 1, len(h.requests))
+	}
+	req := h.requests[0]
+	if req.Get("hub.mode") != "subscribe" {
+		t.Errorf("expected mode %q, got %q", "subscribe", req.Get("hub.mode"))
+	}
+	if req.Get("hub.topic") != topic {
+		t.Errorf("expected topic %q, got %q", topic, req.Get("hub.topic"))
+	}
+	secret := req.Get("hub.secret")
+	if secret == "" {
+		t.Fatalf("expected a secret, got none")
+	}
+
+	sub, err := repo.GetSubscription(feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.State != rss.SubscriptionActive {
+		t.Errorf("expected state %q, got %q", rss.SubscriptionActive, sub.State)
+	}
+	if sub.LeaseExpires == nil {
+		t.Fatalf("expected lease expiry, got none")
+	}
+	if wait := time.Until(*sub.LeaseExpires); wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("expected lease to expire in an hour, got %v", wait)
+	}
+
+	callback := req.Get("hub.callback")
+	testcases := []struct {
+		name      string
+		title     string
+		signature func(body string) string
+		stored    bool
+	}{
+		{"signed", "Signed", func(body string) string { return sign(secret, body) }, true},
+		{"wrong secret", "Forged", func(body string) string { return sign("guess", body) }, false},
+		{"tampered", "Tampered", func(body string) string { return sign(secret, content("Original")) }, false},
+		{"unsigned", "Unsigned", func(body string) string { return "" }, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := content(tc.title)
+			distribute(t, callback, tc.signature(body), body)
+
+			items, err := repo.ListItems(0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var found *rss.Item
+			for _, item := range items {
+				if item.Title == tc.title {
+					found = item
+				}
+			}
+			if !tc.stored {
+				if found != nil {
+					t.Errorf("expected item %q to be ignored, got it stored", tc.title)
+				}
+				return
+			}
+			if found == nil {
+				t.Fatalf("expected item %q to be stored, got none", tc.title)
+			}
+			if found.FeedID != feed.ID {
+				t.Errorf("expected feed ID %d, got %d", feed.ID, found.FeedID)
+			}
+			if found.Link != "https://example.com/posts/1" {
+				t.Errorf("expected link %q, got %q", "https://example.com/posts/1", found.Link)
+			}
+		})
+	}
+}
+
+func TestDistributeReport(t *testing.T) {
+	repo, subscriber, feed, done := setup(t)
+	defer done()
+
+	h := &hub{t: t, leaseSeconds: "3600"}
+	hubServer := httptest.NewServer(h)
+	defer hubServer.Close()
+
+	if err := subscriber.Subscribe(context.Background(), feed.ID, hubServer.URL, topic); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := h.requests[0]
+
+	body := `<rss version="2.0"><channel><title>Example</title><description>D</description><link>https://example.com/</link>` +
+		`<item><title>Good</title><link>/posts/1</link><pubDate>Mon, 01 Apr 2019 10:00:00 GMT</pubDate></item>` +
+		`<item><title>Bad</title><link>/posts/2</link><pubDate>sometime</pubDate></item></channel></rss>`
+	distribute(t, req.Get("hub.callback"), sign(req.Get("hub.secret"), body), body)
+
+	stored, err := repo.GetFeed(feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Report.CheckedAt.IsZero() {
+		t.Errorf("expected the report to be recorded, got none")
+	}
+	if len(stored.Report.Issues) != 1 {
+		t.Fatalf("expected %d issue, got %d: %+v", 1, len(stored.Report.Issues), stored.Report.Issues)
+	}
+	if stored.Report.Issues[0].Value != "sometime" {
+		t.Errorf("expected issue about %q, got %+v", "sometime", stored.Report.Issues[0])
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	repo, subscriber, feed, done := setup(t)
+	defer done()
+
+	h := &hub{t: t, leaseSeconds: "3600"}
+	hubServer := httptest.NewServer(h)
+	defer hubServer.Close()
+
+	if err := subscriber.Subscribe(context.Background(), feed.ID, hubServer.URL, topic); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	callback := h.requests[0].Get("hub.callback")
+
+	// Nobody else can subscribe us to another topic.
+	resp, err := http.Get(callback + "?" + url.Values{
+		"hub.mode":      {"subscribe"},
+		"hub.topic":     {"https://example.com/other.xml"},
+		"hub.challenge": {"x"},
+	}.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	if err := subscriber.Unsubscribe(context.Background(), feed.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(h.requests) != 2 {
+		t.Fatalf("expected %d hub requests, got %d", 2, len(h.requests))
+	}
+	if mode := h.requests[1].Get("hub.mode"); mode != "unsubscribe" {
+		t.Errorf("expected mode %q, got %q", "unsubscribe", mode)
+	}
+	if _, err := repo.GetSubscription(feed.ID); err != rss.ErrSubscriptionNotFound {
+		t.Errorf("expected error %v, got %v", rss.ErrSubscriptionNotFound, err)
+	}
+
+	// Content arriving after unsubscribing is refused.
+	req, err := http.NewRequest(http.MethodPost, callback, strings.NewReader(content("Late")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Errorf("expected status %d, got %d", http.StatusGone, resp.StatusCode)
+	}
+}
+
+func TestSubscribeChannelRetry(t *testing.T) {
+	testcases := []struct {
+		name     string
+		state    string
+		age      time.Duration
+		attempts int
+		retried  bool
+	}{
+		{"active", rss.SubscriptionActive, 48 * time.Hour, 0, false},
+		{"pending", rss.SubscriptionPending, time.Minute, 1, false},
+		{"pending too long", rss.SubscriptionPending, 2 * time.Hour, 1, true},
+		{"denied recently", rss.SubscriptionDenied, time.Hour, 1, false},
+		{"denied a day ago", rss.SubscriptionDenied, 25 * time.Hour, 1, true},
+		{"denied repeatedly", rss.SubscriptionDenied, 25 * time.Hour, 3, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, subscriber, feed, done := setup(t)
+			defer done()
+
+			h := &hub{t: t, leaseSeconds: "3600"}
+			hubServer := httptest.NewServer(h)
+			defer hubServer.Close()
+
+			requestedAt := time.Now().Add(-tc.age)
+			if err := repo.SaveSubscription(&rss.Subscription{
+				FeedID:      feed.ID,
+				Hub:         hubServer.URL,
+				Topic:       topic,
+				Secret:      "s",
+				State:       tc.state,
+				RequestedAt: &requestedAt,
+				Attempts:    tc.attempts,
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			channel := parser.Channel{Links: []parser.Link{{Rel: "hub", Href: hubServer.URL}, {Rel: "self", Href: topic}}}
+			if err := subscriber.SubscribeChannel(context.Background(), feed.ID, channel, topic); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if retried := len(h.requests) > 0; retried != tc.retried {
+				t.Errorf("expected retried to be %v, got %d hub requests", tc.retried, len(h.requests))
+			}
+		})
+	}
+}
+
+func TestSubscribeFailureKeepsSubscription(t *testing.T) {
+	repo, subscriber, feed, done := setup(t)
+	defer done()
+
+	expires := time.Now().Add(time.Hour)
+	existing := &rss.Subscription{
+		FeedID:       feed.ID,
+		Hub:          "https://hub.example.com/",
+		Topic:        topic,
+		Secret:       "s",
+		State:        rss.SubscriptionActive,
+		LeaseExpires: &expires,
+	}
+	if err := repo.SaveSubscription(existing); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	if err := subscriber.Subscribe(context.Background(), feed.ID, failing.URL, topic); err == nil {
+		t.Errorf("expected error, got none")
+	}
+	sub, err := repo.GetSubscription(feed.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Hub != existing.Hub || sub.State != rss.SubscriptionActive {
+		t.Errorf("expected the %s subscription through %s to be kept, got %s through %s", existing.State, existing.Hub, sub.State, sub.Hub)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	testcases := []struct {
+		name         string
+		leaseSeconds string
+		renewed      bool
+	}{
+		{"expiring", "3600", true},
+		{"not expiring", "864000", false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, subscriber, feed, done := setup(t)
+			defer done()
+
+			h := &hub{t: t, leaseSeconds: tc.leaseSeconds}
+			hubServer := httptest.NewServer(h)
+			defer hubServer.Close()
+
+			if err := subscriber.Subscribe(context.Background(), feed.ID, hubServer.URL, topic); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			before, err := repo.GetSubscription(feed.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := subscriber.Renew(context.Background(), time.Now()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.renewed {
+				if len(h.requests) != 1 {
+					t.Errorf("expected no renewal, got %d hub requests", len(h.requests))
+				}
+				return
+			}
+			if len(h.requests) != 2 {
+				t.Fatalf("expected %d hub requests, got %d", 2, len(h.requests))
+			}
+			if secret := h.requests[1].Get("hub.secret"); secret != before.Secret {
+				t.Errorf("expected renewal to keep secret %q, got %q", before.Secret, secret)
+			}
+			after, err := repo.GetSubscription(feed.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if after.State != rss.SubscriptionActive {
+				t.Errorf("expected state %q, got %q", rss.SubscriptionActive, after.State)
+			}
+			if !after.LeaseExpires.After(*before.LeaseExpires) {
+				t.Errorf("expected lease to be extended past %v, got %v", before.LeaseExpires, after.LeaseExpires)
+			}
+		})
+	}
+}
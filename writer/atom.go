@@ -0,0 +1,164 @@
+package writer
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/haleyrc/rss"
+)
+
+// atomFeed is the wire representation of an Atom 1.0 <feed> document. Child
+// elements inherit the Atom namespace from the root.
+type atomFeed struct {
+	XMLName    xml.Name       `xml:"http://www.w3.org/2005/Atom feed"`
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Subtitle   *atomText      `xml:"subtitle"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Logo       string         `xml:"logo,omitempty"`
+	Entries    []atomEntry    `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+	URI   string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term   string `xml:"term,attr"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+// WriteAtom renders feed as an Atom 1.0 document.
+//
+// Atom requires identifiers to be absolute URIs, so items whose GUID is not
+// one are identified by a URN made from it instead. It also requires every entry to
+// have an author; when the feed credits nobody and some of its items have no
+// author, the feed itself is credited by its title.
+func WriteAtom(w io.Writer, feed *rss.Feed) error {
+	f := atomFeed{
+		ID:         feed.Link,
+		Title:      atomText{Type: "text", Body: feed.Title},
+		Authors:    atomPeople(feed.Authors),
+		Categories: atomCategories(feed.Categories),
+		Logo:       feed.Image,
+	}
+	if isAbsoluteURI(feed.URL) {
+		f.ID = feed.URL
+	}
+	if feed.Description != "" && feed.Description != feed.Title {
+		f.Subtitle = &atomText{Type: "text", Body: feed.Description}
+	}
+	if feed.Link != "" {
+		f.Links = append(f.Links, atomLink{Href: feed.Link, Rel: "alternate", Type: "text/html"})
+	}
+	if feed.URL != "" {
+		f.Links = append(f.Links, atomLink{Href: feed.URL, Rel: "self", Type: "application/atom+xml"})
+	}
+	last := updated(feed)
+	if last.IsZero() {
+		last = time.Now()
+	}
+	f.Updated = last.Format(time.RFC3339)
+
+	anonymous := false
+	for _, item := range feed.Items {
+		e := atomEntry{
+			ID:         atomID(item),
+			Title:      atomText{Type: "text", Body: item.Title},
+			Links:      []atomLink{{Href: item.Link, Rel: "alternate"}},
+			Published:  item.PublicationDate.Format(time.RFC3339),
+			Updated:    item.PublicationDate.Format(time.RFC3339),
+			Authors:    atomPeople(item.Authors),
+			Categories: atomCategories(item.Categories),
+		}
+		if item.Summary != "" {
+			e.Summary = &atomText{Type: "html", Body: item.Summary}
+		}
+		if item.Content != "" {
+			e.Content = &atomText{Type: "html", Body: item.Content}
+		}
+		for _, enc := range item.Enclosures {
+			link := atomLink{Href: enc.URL, Rel: "enclosure", Type: enc.Type}
+			if enc.Length > 0 {
+				link.Length = strconv.FormatInt(enc.Length, 10)
+			}
+			e.Links = append(e.Links, link)
+		}
+		if len(e.Authors) == 0 {
+			anonymous = true
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	if anonymous && len(f.Authors) == 0 {
+		f.Authors = []atomPerson{{Name: feed.Title}}
+	}
+
+	return writeXML(w, f)
+}
+
+// atomPeople converts authors, leaving out those without a name, which Atom
+// requires.
+func atomPeople(authors []*rss.Author) []atomPerson {
+	var people []atomPerson
+	for _, a := range authors {
+		if a.Name == "" {
+			continue
+		}
+		people = append(people, atomPerson{Name: a.Name, Email: a.Email, URI: a.URL})
+	}
+	return people
+}
+
+func atomCategories(categories []*rss.Category) []atomCategory {
+	var out []atomCategory
+	for _, c := range categories {
+		out = append(out, atomCategory{Term: c.Name, Scheme: c.Domain})
+	}
+	return out
+}
+
+// atomID returns the identifier of an item as an absolute URI. Wrapping a GUID
+// that is not one, rather than falling back to the link, keeps items that
+// share a link apart.
+func atomID(item *rss.Item) string {
+	switch {
+	case item.GUID == "":
+		return item.Link
+	case isAbsoluteURI(item.GUID):
+		return item.GUID
+	default:
+		return "urn:guid:" + url.PathEscape(item.GUID)
+	}
+}
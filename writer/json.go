@@ -0,0 +1,130 @@
+package writer
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/haleyrc/rss"
+)
+
+// jsonVersion identifies the version of JSON Feed that is written.
+const jsonVersion = "https://jsonfeed.org/version/1.1"
+
+// jsonFeed is the wire representation of a JSON Feed 1.1 document.
+type jsonFeed struct {
+	Version     string       `json:"version"`
+	Title       string       `json:"title"`
+	HomePageURL string       `json:"home_page_url,omitempty"`
+	FeedURL     string       `json:"feed_url,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Icon        string       `json:"icon,omitempty"`
+	Authors     []jsonAuthor `json:"authors,omitempty"`
+	Items       []jsonItem   `json:"items"`
+}
+
+type jsonItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	Authors       []jsonAuthor     `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []jsonAttachment `json:"attachments,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+type jsonAttachment struct {
+	URL               string `json:"url"`
+	MimeType          string `json:"mime_type"`
+	SizeInBytes       int64  `json:"size_in_bytes,omitempty"`
+	DurationInSeconds int64  `json:"duration_in_seconds,omitempty"`
+}
+
+// WriteJSON renders feed as a JSON Feed 1.1 document. Every item must have
+// some content, so the summary stands in for items without any. JSON Feed has
+// no place for email addresses, so authors are written without them, and
+// categories become tags. The duration of an episode is only written when it
+// is clear which attachment it belongs to.
+func WriteJSON(w io.Writer, feed *rss.Feed) error {
+	f := jsonFeed{
+		Version:     jsonVersion,
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.URL,
+		Description: feed.Description,
+		Icon:        feed.Image,
+		Authors:     jsonAuthors(feed.Authors),
+		Items:       []jsonItem{},
+	}
+	for _, item := range feed.Items {
+		i := jsonItem{
+			ID:            itemID(item),
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.Content,
+			Summary:       item.Summary,
+			Image:         item.LeadImage,
+			DatePublished: item.PublicationDate.Format(time.RFC3339),
+			Authors:       jsonAuthors(item.Authors),
+		}
+		if i.ContentHTML == "" {
+			i.ContentHTML = item.Summary
+		}
+		for _, c := range item.Categories {
+			i.Tags = append(i.Tags, c.Name)
+		}
+		ep := episode(item)
+		for _, e := range item.Enclosures {
+			a := jsonAttachment{URL: e.URL, MimeType: e.Type, SizeInBytes: e.Length}
+			if e == ep {
+				a.DurationInSeconds = item.Duration
+			}
+			i.Attachments = append(i.Attachments, a)
+		}
+		f.Items = append(f.Items, i)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// episode returns the enclosure that the duration of an item belongs to, which
+// we only know if the item has a single audio or video enclosure.
+func episode(item *rss.Item) *rss.Enclosure {
+	var found *rss.Enclosure
+	for _, e := range item.Enclosures {
+		if !strings.HasPrefix(e.Type, "audio/") && !strings.HasPrefix(e.Type, "video/") {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = e
+	}
+	return found
+}
+
+// jsonAuthors converts authors, leaving out those known only by their email
+// address.
+func jsonAuthors(authors []*rss.Author) []jsonAuthor {
+	var out []jsonAuthor
+	for _, a := range authors {
+		if a.Name == "" && a.URL == "" {
+			continue
+		}
+		out = append(out, jsonAuthor{Name: a.Name, URL: a.URL, Avatar: a.Avatar})
+	}
+	return out
+}
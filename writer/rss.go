@@ -0,0 +1,168 @@
+package writer
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/haleyrc/rss"
+)
+
+// rssDocument is the wire representation of an RSS 2.0 document. Extension
+// elements are written with their conventional prefixes, which are declared
+// on the root element.
+type rssDocument struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string        `xml:"title"`
+	Link           string        `xml:"link"`
+	Description    string        `xml:"description"`
+	Self           *rssAtomLink  `xml:"atom:link"`
+	ManagingEditor string        `xml:"managingEditor,omitempty"`
+	LastBuildDate  string        `xml:"lastBuildDate,omitempty"`
+	Categories     []rssCategory `xml:"category"`
+	TTL            int           `xml:"ttl,omitempty"`
+	Image          *rssImage     `xml:"image"`
+	Items          []rssItem     `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssItem struct {
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	Description string         `xml:"description,omitempty"`
+	Content     string         `xml:"content:encoded,omitempty"`
+	Author      string         `xml:"author,omitempty"`
+	Creators    []string       `xml:"dc:creator"`
+	Categories  []rssCategory  `xml:"category"`
+	Enclosures  []rssEnclosure `xml:"enclosure"`
+	GUID        *rssGUID       `xml:"guid"`
+	PubDate     string         `xml:"pubDate"`
+}
+
+type rssCategory struct {
+	Domain string `xml:"domain,attr,omitempty"`
+	Name   string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// WriteRSS renders feed as an RSS 2.0 document. RSS only has room for one
+// author of an item, given by email address, so the first author with an
+// address is written as the author and everyone is credited with dc:creator.
+func WriteRSS(w io.Writer, feed *rss.Feed) error {
+	c := rssChannel{
+		Title:          feed.Title,
+		Link:           feed.Link,
+		Description:    feed.Description,
+		ManagingEditor: rssByline(feed.Authors),
+		Categories:     rssCategories(feed.Categories),
+		TTL:            feed.Schedule.TTL,
+	}
+	if feed.URL != "" {
+		c.Self = &rssAtomLink{Href: feed.URL, Rel: "self", Type: "application/rss+xml"}
+	}
+	if t := updated(feed); !t.IsZero() {
+		c.LastBuildDate = t.Format(time.RFC1123Z)
+	}
+	if feed.Image != "" {
+		c.Image = &rssImage{URL: feed.Image, Title: feed.Title, Link: feed.Link}
+	}
+
+	for _, item := range feed.Items {
+		i := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Summary,
+			Content:     item.Content,
+			Author:      rssByline(item.Authors),
+			Categories:  rssCategories(item.Categories),
+			PubDate:     item.PublicationDate.Format(time.RFC1123Z),
+		}
+		if item.GUID != "" {
+			i.GUID = &rssGUID{IsPermaLink: item.GUID == item.Link, Value: item.GUID}
+		}
+		for _, a := range item.Authors {
+			if a.Name != "" {
+				i.Creators = append(i.Creators, a.Name)
+			}
+		}
+		for _, e := range item.Enclosures {
+			i.Enclosures = append(i.Enclosures, rssEnclosure{URL: e.URL, Length: e.Length, Type: e.Type})
+		}
+		c.Items = append(c.Items, i)
+	}
+
+	doc := rssDocument{
+		Version:   "2.0",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		Channel:   c,
+	}
+	return writeXML(w, doc)
+}
+
+// rssByline returns the first author with an email address in the form the
+// RSS specification asks for, or an empty string if nobody has one.
+func rssByline(authors []*rss.Author) string {
+	for _, a := range authors {
+		if a.Email == "" {
+			continue
+		}
+		if a.Name == "" {
+			return a.Email
+		}
+		return a.Email + " (" + a.Name + ")"
+	}
+	return ""
+}
+
+func rssCategories(categories []*rss.Category) []rssCategory {
+	var out []rssCategory
+	for _, c := range categories {
+		out = append(out, rssCategory{Domain: c.Domain, Name: c.Name})
+	}
+	return out
+}
+
+// writeXML writes doc as an indented XML document with a declaration.
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,72 @@
+// Package writer renders feeds as RSS 2.0, Atom 1.0 or JSON Feed 1.1
+// documents, so that aggregated or filtered views of our subscriptions can be
+// republished.
+//
+// Feeds are written from what we store rather than what was published, so
+// some details are lost along the way; but a written feed read back through
+// the parser and rss.NewFromFeed gives the same feed and items. The one
+// exception is that Atom identifiers must be URIs, so a GUID that is not one
+// comes back from an Atom feed as a urn:guid: URN.
+package writer
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+)
+
+// ContentTypes are the media types of the documents written in each format.
+var ContentTypes = map[parser.Format]string{
+	parser.FormatRSS:  "application/rss+xml; charset=utf-8",
+	parser.FormatAtom: "application/atom+xml; charset=utf-8",
+	parser.FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// Write renders feed in the given format, which may be RSS, Atom or JSON Feed.
+func Write(w io.Writer, feed *rss.Feed, format parser.Format) error {
+	switch format {
+	case parser.FormatRSS:
+		return WriteRSS(w, feed)
+	case parser.FormatAtom:
+		return WriteAtom(w, feed)
+	case parser.FormatJSON:
+		return WriteJSON(w, feed)
+	default:
+		return errors.Errorf("can not write feeds as %q", format)
+	}
+}
+
+// updated returns the time a feed last changed, which we take to be when its
+// most recent item was published. Feeds without items have never changed, so
+// the zero time is returned.
+func updated(feed *rss.Feed) time.Time {
+	var latest time.Time
+	for _, item := range feed.Items {
+		if item.PublicationDate.After(latest) {
+			latest = item.PublicationDate
+		}
+	}
+	return latest
+}
+
+// itemID returns a permanent identifier for an item. Items are identified by
+// their GUID where they have one, and by their link otherwise.
+func itemID(item *rss.Item) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	return item.Link
+}
+
+// isAbsoluteURI reports whether s is an absolute URI, as Atom requires of
+// identifiers.
+func isAbsoluteURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && !strings.ContainsAny(s, " \t\n")
+}
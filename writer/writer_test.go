@@ -0,0 +1,254 @@
+package writer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/haleyrc/rss"
+	"github.com/haleyrc/rss/parser"
+	"github.com/haleyrc/rss/writer"
+)
+
+func testFeed() *rss.Feed {
+	jane := &rss.Author{Name: "Jane Doe", Email: "jane@example.com", URL: "https://example.com/jane"}
+	return &rss.Feed{
+		Title:       `Tom & Jerry's "Blog"`,
+		Description: `News & "views"`,
+		Link:        "https://example.com/",
+		Image:       "https://example.com/logo.png",
+		URL:         "https://example.com/feed",
+		Authors:     []*rss.Author{jane},
+		Categories:  []*rss.Category{{Name: "Go"}},
+		Items: []*rss.Item{
+			{
+				GUID:            "https://example.com/posts/1?a=1&b=2",
+				Title:           `Fish & "Chips" <2>`,
+				Link:            "https://example.com/posts/1?a=1&b=2",
+				PublicationDate: time.Date(2019, 4, 1, 10, 0, 0, 0, time.FixedZone("", 2*60*60)),
+				Summary:         `<p>5 &lt; 6 &amp; 7</p>`,
+				Content:         "<p>Caf\u00e9 ]]&gt; <a href=\"https://example.com/?x=1&amp;y=2\">link</a></p>",
+				Authors:         []*rss.Author{jane},
+				Categories:      []*rss.Category{{Name: "Go"}, {Name: "News & Views", Domain: "https://example.com/tags"}},
+				Enclosures:      []*rss.Enclosure{{URL: "https://example.com/1.mp3", Type: "audio/mpeg", Length: 1234}},
+			},
+			{
+				GUID:            "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a",
+				Title:           "Second",
+				Link:            "https://example.com/posts/2",
+				PublicationDate: time.Date(2019, 4, 2, 8, 30, 0, 0, time.UTC),
+				Summary:         "<p>Only a summary</p>",
+				Content:         "<p>And some content</p>",
+				Authors:         []*rss.Author{{Name: "John Roe"}},
+			},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	testcases := []struct {
+		format  parser.Format
+		domains bool
+	}{
+		{parser.FormatRSS, true},
+		{parser.FormatAtom, true},
+		{parser.FormatJSON, false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(string(tc.format), func(t *testing.T) {
+			want := testFeed()
+			var buf bytes.Buffer
+			if err := writer.Write(&buf, want, tc.format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			parsed, err := parser.Load(&buf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if parsed.Format != tc.format {
+				t.Errorf("expected format %q, got %q", tc.format, parsed.Format)
+			}
+			if len(parsed.Repairs) != 0 {
+				t.Errorf("expected a well-formed document, got repairs %v", parsed.Repairs)
+			}
+			if self := parsed.Channel.SelfLink(); self != want.URL {
+				t.Errorf("expected self link %q, got %q", want.URL, self)
+			}
+			got, err := rss.NewFromFeed(parsed)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got.Report.Issues) != 0 {
+				t.Errorf("expected no validation issues, got %+v", got.Report.Issues)
+			}
+
+			if got.Title != want.Title {
+				t.Errorf("expected title %q, got %q", want.Title, got.Title)
+			}
+			if got.Description != want.Description {
+				t.Errorf("expected description %q, got %q", want.Description, got.Description)
+			}
+			if got.Link != want.Link {
+				t.Errorf("expected link %q, got %q", want.Link, got.Link)
+			}
+			if got.Image != want.Image {
+				t.Errorf("expected image %q, got %q", want.Image, got.Image)
+			}
+			if names(got.Authors) != names(want.Authors) {
+				t.Errorf("expected authors %q, got %q", names(want.Authors), names(got.Authors))
+			}
+			if len(got.Items) != len(want.Items) {
+				t.Fatalf("expected %d items, got %d", len(want.Items), len(got.Items))
+			}
+
+			for i, w := range want.Items {
+				g := got.Items[i]
+				if g.GUID != w.GUID {
+					t.Errorf("expected guid %q, got %q", w.GUID, g.GUID)
+				}
+				if g.Title != w.Title {
+					t.Errorf("expected title %q, got %q", w.Title, g.Title)
+				}
+				if g.Link != w.Link {
+					t.Errorf("expected link %q, got %q", w.Link, g.Link)
+				}
+				if !g.PublicationDate.Equal(w.PublicationDate) {
+					t.Errorf("expected publication date %v, got %v", w.PublicationDate, g.PublicationDate)
+				}
+				if g.Summary != w.Summary {
+					t.Errorf("expected summary %q, got %q", w.Summary, g.Summary)
+				}
+				if g.Content != w.Content {
+					t.Errorf("expected content %q, got %q", w.Content, g.Content)
+				}
+				if names(g.Authors) != names(w.Authors) {
+					t.Errorf("expected authors %q, got %q", names(w.Authors), names(g.Authors))
+				}
+
+				var wantCategories []rss.Category
+				for _, c := range w.Categories {
+					if !tc.domains {
+						c = &rss.Category{Name: c.Name}
+					}
+					wantCategories = append(wantCategories, *c)
+				}
+				var gotCategories []rss.Category
+				for _, c := range g.Categories {
+					gotCategories = append(gotCategories, *c)
+				}
+				if !reflect.DeepEqual(gotCategories, wantCategories) {
+					t.Errorf("expected categories %+v, got %+v", wantCategories, gotCategories)
+				}
+
+				if len(g.Enclosures) != len(w.Enclosures) {
+					t.Fatalf("expected %d enclosures, got %d", len(w.Enclosures), len(g.Enclosures))
+				}
+				for j, e := range w.Enclosures {
+					if *g.Enclosures[j] != *e {
+						t.Errorf("expected enclosure %+v, got %+v", *e, *g.Enclosures[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestWriteAtomIdentifiers(t *testing.T) {
+	feed := testFeed()
+	feed.Authors = nil
+	feed.Items[0].GUID = "post 1"
+	feed.Items[1].GUID = "post-2"
+	feed.Items[1].Link = feed.Items[0].Link
+	feed.Items[1].Authors = nil
+
+	var buf bytes.Buffer
+	if err := writer.WriteAtom(&buf, feed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := parser.Load(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := rss.NewFromFeed(parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, want := range []string{"urn:guid:post%201", "urn:guid:post-2"} {
+		if got.Items[i].GUID != want {
+			t.Errorf("expected guid %q, got %q", want, got.Items[i].GUID)
+		}
+	}
+	if names(got.Items[1].Authors) != feed.Title {
+		t.Errorf("expected author %q, got %q", feed.Title, names(got.Items[1].Authors))
+	}
+}
+
+func TestWriteJSONDuration(t *testing.T) {
+	audio := &rss.Enclosure{URL: "https://example.com/1.mp3", Type: "audio/mpeg"}
+	video := &rss.Enclosure{URL: "https://example.com/1.mp4", Type: "video/mp4"}
+	image := &rss.Enclosure{URL: "https://example.com/1.jpg", Type: "image/jpeg"}
+
+	testcases := []struct {
+		name       string
+		enclosures []*rss.Enclosure
+		want       []int64
+	}{
+		{"episode", []*rss.Enclosure{audio}, []int64{61}},
+		{"episode and cover", []*rss.Enclosure{image, audio}, []int64{0, 61}},
+		{"audio and video", []*rss.Enclosure{audio, video}, []int64{0, 0}},
+		{"no episode", []*rss.Enclosure{image}, []int64{0}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed := testFeed()
+			feed.Items = feed.Items[:1]
+			feed.Items[0].Enclosures = tc.enclosures
+			feed.Items[0].Duration = 61
+
+			var buf bytes.Buffer
+			if err := writer.WriteJSON(&buf, feed); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var doc struct {
+				Items []struct {
+					Attachments []struct {
+						DurationInSeconds int64 `json:"duration_in_seconds"`
+					} `json:"attachments"`
+				} `json:"items"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []int64
+			for _, a := range doc.Items[0].Attachments {
+				got = append(got, a.DurationInSeconds)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected durations %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writer.Write(&buf, testFeed(), parser.FormatRDF); err == nil {
+		t.Errorf("expected error, got none")
+	}
+}
+
+func names(authors []*rss.Author) string {
+	var s string
+	for i, a := range authors {
+		if i > 0 {
+			s += ", "
+		}
+		s += a.Name
+	}
+	return s
+}